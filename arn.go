@@ -0,0 +1,94 @@
+package elblog
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ARN is Amazon Resource Name: arn:partition:service:region:account-id:resource.
+type ARN struct {
+	Partition string
+	Service   string
+	Region    string
+	AccountID string
+	// Resource is the part after account ID, e.g. targetgroup/my-targets/73e2d6bc24d8a067. It may contain colons.
+	Resource string
+}
+
+// ParseARN parses ARN, see ARN.
+func ParseARN(s string) (ARN, error) {
+	parts := strings.SplitN(s, ":", 6)
+	if len(parts) != 6 || parts[0] != "arn" || parts[1] == "" || parts[2] == "" || parts[5] == "" {
+		return ARN{}, fmt.Errorf("invalid ARN %q", s)
+	}
+	return ARN{
+		Partition: parts[1],
+		Service:   parts[2],
+		Region:    parts[3],
+		AccountID: parts[4],
+		Resource:  parts[5],
+	}, nil
+}
+
+// String returns ARN in its textual form. It returns an empty string for the zero ARN.
+func (a ARN) String() string {
+	if a == (ARN{}) {
+		return ""
+	}
+	return "arn:" + a.Partition + ":" + a.Service + ":" + a.Region + ":" + a.AccountID + ":" + a.Resource
+}
+
+// TargetGroup is ARN of a target group: arn:aws:elasticloadbalancing:region:account-id:targetgroup/name/id.
+type TargetGroup struct {
+	ARN
+	Name string
+	ID   string
+}
+
+// TargetGroup returns TargetGroupARN decomposed. It returns the zero TargetGroup if the field is "-" or missing,
+// e.g. if the request was not forwarded to a target.
+func (l *Log) TargetGroup() (TargetGroup, error) {
+	if isEmptyField(l.TargetGroupARN) {
+		return TargetGroup{}, nil
+	}
+	arn, err := ParseARN(l.TargetGroupARN)
+	if err != nil {
+		return TargetGroup{}, err
+	}
+	kind, rest, _ := strings.Cut(arn.Resource, "/")
+	name, id, ok := strings.Cut(rest, "/")
+	if kind != "targetgroup" || !ok || name == "" || id == "" {
+		return TargetGroup{}, fmt.Errorf("invalid target group ARN %q", l.TargetGroupARN)
+	}
+	return TargetGroup{ARN: arn, Name: name, ID: id}, nil
+}
+
+// Certificate is ARN of a certificate presented to the client: arn:aws:acm:region:account-id:certificate/id
+// for ACM certificates or arn:aws:iam::account-id:server-certificate/name for IAM ones.
+type Certificate struct {
+	ARN
+	// ID is the certificate ID for ACM certificates or the name, including path, for IAM ones.
+	ID string
+	// SessionReused is true if the TLS session was reused, the certificate is not logged then.
+	SessionReused bool
+}
+
+// Certificate returns ChosenCertARN decomposed. It returns the zero Certificate if the field is "-" or missing,
+// or Certificate with SessionReused set only if the field is "session-reused".
+func (l *Log) Certificate() (Certificate, error) {
+	if isEmptyField(l.ChosenCertARN) {
+		return Certificate{}, nil
+	}
+	if l.ChosenCertARN == "session-reused" {
+		return Certificate{SessionReused: true}, nil
+	}
+	arn, err := ParseARN(l.ChosenCertARN)
+	if err != nil {
+		return Certificate{}, err
+	}
+	_, id, ok := strings.Cut(arn.Resource, "/")
+	if !ok || id == "" {
+		return Certificate{}, fmt.Errorf("invalid certificate ARN %q", l.ChosenCertARN)
+	}
+	return Certificate{ARN: arn, ID: id}, nil
+}
@@ -0,0 +1,117 @@
+package elblog
+
+import (
+	"testing"
+)
+
+func TestParseARN(t *testing.T) {
+	cases := map[string]struct {
+		given    string
+		expected ARN
+		err      bool
+	}{
+		"target-group": {
+			given:    "arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067",
+			expected: ARN{Partition: "aws", Service: "elasticloadbalancing", Region: "us-east-2", AccountID: "123456789012", Resource: "targetgroup/my-targets/73e2d6bc24d8a067"},
+		},
+		"no-region": {
+			given:    "arn:aws:iam::123456789012:server-certificate/my-cert",
+			expected: ARN{Partition: "aws", Service: "iam", AccountID: "123456789012", Resource: "server-certificate/my-cert"},
+		},
+		"colon-in-resource": {
+			given:    "arn:aws-cn:lambda:cn-north-1:123456789012:function:my-function",
+			expected: ARN{Partition: "aws-cn", Service: "lambda", Region: "cn-north-1", AccountID: "123456789012", Resource: "function:my-function"},
+		},
+		"dash":        {given: "-", err: true},
+		"no-prefix":   {given: "aws:acm:us-east-2:123456789012:certificate/1234:x", err: true},
+		"no-resource": {given: "arn:aws:acm:us-east-2:123456789012:", err: true},
+	}
+
+	for hint, c := range cases {
+		t.Run(hint, func(t *testing.T) {
+			got, err := ParseARN(c.given)
+			if (err != nil) != c.err {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != c.expected {
+				t.Errorf("expected:\n	%v but got:\n	%v", c.expected, got)
+			}
+			if err == nil && got.String() != c.given {
+				t.Errorf("expected %q but got %q", c.given, got.String())
+			}
+		})
+	}
+}
+
+func TestLog_TargetGroup(t *testing.T) {
+	cases := map[string]struct {
+		given    string
+		expected TargetGroup
+		err      bool
+	}{
+		"valid": {
+			given: "arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067",
+			expected: TargetGroup{
+				ARN:  ARN{Partition: "aws", Service: "elasticloadbalancing", Region: "us-east-2", AccountID: "123456789012", Resource: "targetgroup/my-targets/73e2d6bc24d8a067"},
+				Name: "my-targets",
+				ID:   "73e2d6bc24d8a067",
+			},
+		},
+		"dash":        {given: "-"},
+		"missing":     {given: ""},
+		"not-target":  {given: "arn:aws:acm:us-east-2:123456789012:certificate/12345678-1234-1234-1234-123456789012", err: true},
+		"missing-id":  {given: "arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets", err: true},
+		"invalid-arn": {given: "targetgroup/my-targets/73e2d6bc24d8a067", err: true},
+	}
+
+	for hint, c := range cases {
+		t.Run(hint, func(t *testing.T) {
+			got, err := (&Log{TargetGroupARN: c.given}).TargetGroup()
+			if (err != nil) != c.err {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != c.expected {
+				t.Errorf("expected:\n	%v but got:\n	%v", c.expected, got)
+			}
+		})
+	}
+}
+
+func TestLog_Certificate(t *testing.T) {
+	cases := map[string]struct {
+		given    string
+		expected Certificate
+		err      bool
+	}{
+		"acm": {
+			given: "arn:aws:acm:us-east-2:123456789012:certificate/12345678-1234-1234-1234-123456789012",
+			expected: Certificate{
+				ARN: ARN{Partition: "aws", Service: "acm", Region: "us-east-2", AccountID: "123456789012", Resource: "certificate/12345678-1234-1234-1234-123456789012"},
+				ID:  "12345678-1234-1234-1234-123456789012",
+			},
+		},
+		"iam": {
+			given: "arn:aws:iam::123456789012:server-certificate/division/my-cert",
+			expected: Certificate{
+				ARN: ARN{Partition: "aws", Service: "iam", AccountID: "123456789012", Resource: "server-certificate/division/my-cert"},
+				ID:  "division/my-cert",
+			},
+		},
+		"session-reused": {given: "session-reused", expected: Certificate{SessionReused: true}},
+		"dash":           {given: "-"},
+		"missing":        {given: ""},
+		"missing-id":     {given: "arn:aws:acm:us-east-2:123456789012:certificate", err: true},
+	}
+
+	for hint, c := range cases {
+		t.Run(hint, func(t *testing.T) {
+			got, err := (&Log{ChosenCertARN: c.given}).Certificate()
+			if (err != nil) != c.err {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != c.expected {
+				t.Errorf("expected:\n	%v but got:\n	%v", c.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package elblog
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrCheckpointCompressed is returned by NewDecoderAt for compressed input, offsets of which cannot be sought.
+var ErrCheckpointCompressed = errors.New("elblog: cannot resume decoding of compressed input")
+
+// Checkpoint is a position in the input right after a decoded line. It can be persisted to resume decoding
+// from it, see NewDecoderAt.
+type Checkpoint struct {
+	// Offset is the byte offset at which decoding is resumed.
+	Offset int64
+	// Line is the number of the last decoded line, decoding is resumed with the next one.
+	Line int
+}
+
+// Checkpoint returns the position right after the line last returned by Decode. Resuming from it
+// decodes the rest of the input without duplicates. Lines skipped after the last returned one are decoded again.
+func (d *Decoder) Checkpoint() Checkpoint {
+	return Checkpoint{
+		Offset: d.offset,
+		Line:   d.line,
+	}
+}
+
+// NewDecoderAt allocates new Decoder object for given input, that resumes decoding from the checkpoint.
+// The input is sought to the checkpoint offset, which has to be at the beginning of a line, e.g. as returned
+// by Decoder.Checkpoint. Line numbers and offsets reported by Decoder continue from the checkpoint.
+// It returns ErrCheckpointCompressed if WithGzip is set, as offsets refer to the decompressed input.
+func NewDecoderAt(rs io.ReadSeeker, cp Checkpoint, opts ...DecoderOption) (*Decoder, error) {
+	d := NewDecoder(rs, opts...)
+	if d.lines.decompress {
+		return nil, ErrCheckpointCompressed
+	}
+	if _, err := rs.Seek(cp.Offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	d.line, d.offset = cp.Line, cp.Offset
+	d.lines.lines, d.lines.line = cp.Line, cp.Line
+	d.lines.scanned, d.lines.offset = cp.Offset, cp.Offset
+	return d, nil
+}
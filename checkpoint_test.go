@@ -0,0 +1,127 @@
+package elblog
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewDecoderAt(t *testing.T) {
+	data, err := os.ReadFile("data.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected []*Log
+	for log, err := range All(bytes.NewReader(data)) {
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		expected = append(expected, log)
+	}
+
+	for stop := range len(expected) + 1 {
+		dec := NewDecoder(bytes.NewReader(data))
+		got := make([]*Log, 0, len(expected))
+		for range stop {
+			log, err := dec.Decode()
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+			got = append(got, log)
+		}
+		// More reads ahead, the checkpoint must not include it
+		dec.More()
+		cp := dec.Checkpoint()
+		if cp.Line != stop {
+			t.Errorf("wrong checkpoint line, expected %d but got %d", stop, cp.Line)
+		}
+
+		dec, err = NewDecoderAt(bytes.NewReader(data), cp)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		for log, err := range dec.All() {
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+			got = append(got, log)
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("resuming after %d logs, decoded logs are different", stop)
+		}
+		if dec.Line() != len(expected) || dec.Offset() != int64(len(data)) {
+			t.Errorf("resuming after %d logs, wrong position: %d, %d", stop, dec.Line(), dec.Offset())
+		}
+	}
+}
+
+func TestNewDecoderAt_lineNumbers(t *testing.T) {
+	input := strings.Join([]string{validLine, validLine, invalidLine, validLine}, "\n")
+	cp := Checkpoint{Offset: int64(2 * (len(validLine) + 1)), Line: 2}
+	dec, err := NewDecoderAt(strings.NewReader(input), cp)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	_, err = dec.Decode()
+	var perr *ParseError
+	if !errors.As(err, &perr) || perr.Line != 3 {
+		t.Fatalf("expected ParseError at line 3 but got: %v", err)
+	}
+	if _, err := dec.Decode(); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if dec.Line() != 4 || dec.Offset() != int64(len(input)) {
+		t.Errorf("wrong position: %d, %d", dec.Line(), dec.Offset())
+	}
+}
+
+func TestNewDecoderAt_compressed(t *testing.T) {
+	if _, err := NewDecoderAt(strings.NewReader(validLine), Checkpoint{}, WithGzip()); err != ErrCheckpointCompressed {
+		t.Errorf("expected ErrCheckpointCompressed, got: %v", err)
+	}
+}
+
+func TestNewDecoderAt_seekError(t *testing.T) {
+	if _, err := NewDecoderAt(strings.NewReader(validLine), Checkpoint{Offset: -1}); err == nil || err == io.EOF {
+		t.Errorf("expected seek error, got: %v", err)
+	}
+}
+
+func TestNewDecoderAt_longLine(t *testing.T) {
+	long := strings.Replace(validLine, "curl/7.38.0", strings.Repeat("x", 10000), 1)
+	input := strings.Join([]string{validLine, long, validLine}, "\n")
+	opts := []DecoderOption{WithMaxLineSize(len(validLine)), WithLongLinePolicy(LongLineTruncate)}
+
+	dec := NewDecoder(strings.NewReader(input), opts...)
+	for range 2 {
+		if _, err := dec.Decode(); err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+	}
+	cp := dec.Checkpoint()
+	if expected := int64(len(validLine) + len(long) + 2); cp.Line != 2 || cp.Offset != expected {
+		t.Fatalf("checkpoint should be right after the truncated line, expected 2, %d but got %d, %d", expected, cp.Line, cp.Offset)
+	}
+
+	dec, err := NewDecoderAt(strings.NewReader(input), cp, opts...)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	var n int
+	for log, err := range dec.All() {
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		if log.UserAgent != "curl/7.38.0" {
+			t.Errorf("rest of the truncated line should not be decoded, got: %s", log.Request)
+		}
+		n++
+	}
+	if n != 1 || dec.Line() != 3 {
+		t.Errorf("expected 1 log at line 3, got %d logs at line %d", n, dec.Line())
+	}
+}
@@ -0,0 +1,167 @@
+package elblog
+
+// Classification is the desync mitigation classification of the request.
+// See https://docs.aws.amazon.com/elasticloadbalancing/latest/application/application-load-balancers.html#desync-mitigation-mode
+type Classification string
+
+// Classifications logged by ALB.
+const (
+	// ClassificationNone is logged if the request complies with RFC 7230.
+	ClassificationNone Classification = "-"
+	// ClassificationUnknown is returned by Normalize for values that are not documented.
+	ClassificationUnknown Classification = "Unknown"
+
+	ClassificationAcceptable Classification = "Acceptable"
+	ClassificationAmbiguous  Classification = "Ambiguous"
+	ClassificationSevere     Classification = "Severe"
+)
+
+var classificationDescriptions = map[Classification]string{
+	ClassificationAcceptable: "The request does not comply with RFC 7230 but poses no known security threats.",
+	ClassificationAmbiguous:  "The request does not comply with RFC 7230 but poses a risk, as various web servers and proxies could handle it differently.",
+	ClassificationSevere:     "The request poses a high security risk. The load balancer blocks the request, serves a 400 response to the client, and closes the client connection.",
+}
+
+// Known returns true if c is one of the documented classifications.
+func (c Classification) Known() bool {
+	_, ok := classificationDescriptions[c]
+	return ok
+}
+
+// Normalize returns ClassificationNone if c is "-" or empty, c if it is known and ClassificationUnknown otherwise.
+func (c Classification) Normalize() Classification {
+	switch {
+	case isEmptyField(string(c)):
+		return ClassificationNone
+	case c.Known():
+		return c
+	default:
+		return ClassificationUnknown
+	}
+}
+
+// Description returns the description of c from AWS documentation, or an empty string if c is not known.
+func (c Classification) Description() string {
+	return classificationDescriptions[c]
+}
+
+// ErrorReason is the reason an authenticate action or a Lambda target failed.
+// See https://docs.aws.amazon.com/elasticloadbalancing/latest/application/load-balancer-access-logs.html#error-reason-codes
+type ErrorReason string
+
+// Error reasons logged by ALB.
+const (
+	// ErrorReasonNone is logged if there was no error.
+	ErrorReasonNone ErrorReason = "-"
+	// ErrorReasonUnknown is returned by Normalize for values that are not documented.
+	ErrorReasonUnknown ErrorReason = "Unknown"
+
+	ErrorReasonAuthInvalidCookie                          ErrorReason = "AuthInvalidCookie"
+	ErrorReasonAuthInvalidGrantError                      ErrorReason = "AuthInvalidGrantError"
+	ErrorReasonAuthInvalidIdToken                         ErrorReason = "AuthInvalidIdToken"
+	ErrorReasonAuthInvalidStateParam                      ErrorReason = "AuthInvalidStateParam"
+	ErrorReasonAuthInvalidTokenResponse                   ErrorReason = "AuthInvalidTokenResponse"
+	ErrorReasonAuthInvalidUserinfoResponse                ErrorReason = "AuthInvalidUserinfoResponse"
+	ErrorReasonAuthMissingCodeParam                       ErrorReason = "AuthMissingCodeParam"
+	ErrorReasonAuthMissingHostHeader                      ErrorReason = "AuthMissingHostHeader"
+	ErrorReasonAuthMissingStateParam                      ErrorReason = "AuthMissingStateParam"
+	ErrorReasonAuthTokenEpRequestFailed                   ErrorReason = "AuthTokenEpRequestFailed"
+	ErrorReasonAuthTokenEpRequestTimeout                  ErrorReason = "AuthTokenEpRequestTimeout"
+	ErrorReasonAuthUnhandledException                     ErrorReason = "AuthUnhandledException"
+	ErrorReasonAuthUserinfoEpRequestFailed                ErrorReason = "AuthUserinfoEpRequestFailed"
+	ErrorReasonAuthUserinfoEpRequestTimeout               ErrorReason = "AuthUserinfoEpRequestTimeout"
+	ErrorReasonAuthUserinfoResponseSizeExceeded           ErrorReason = "AuthUserinfoResponseSizeExceeded"
+	ErrorReasonLambdaAccessDenied                         ErrorReason = "LambdaAccessDenied"
+	ErrorReasonLambdaBadRequest                           ErrorReason = "LambdaBadRequest"
+	ErrorReasonLambdaConnectionError                      ErrorReason = "LambdaConnectionError"
+	ErrorReasonLambdaConnectionTimeout                    ErrorReason = "LambdaConnectionTimeout"
+	ErrorReasonLambdaEC2AccessDeniedException             ErrorReason = "LambdaEC2AccessDeniedException"
+	ErrorReasonLambdaEC2ThrottledException                ErrorReason = "LambdaEC2ThrottledException"
+	ErrorReasonLambdaEC2UnexpectedException               ErrorReason = "LambdaEC2UnexpectedException"
+	ErrorReasonLambdaENILimitReachedException             ErrorReason = "LambdaENILimitReachedException"
+	ErrorReasonLambdaInvalidResponse                      ErrorReason = "LambdaInvalidResponse"
+	ErrorReasonLambdaInvalidRuntimeException              ErrorReason = "LambdaInvalidRuntimeException"
+	ErrorReasonLambdaInvalidSecurityGroupIDException      ErrorReason = "LambdaInvalidSecurityGroupIDException"
+	ErrorReasonLambdaInvalidSubnetIDException             ErrorReason = "LambdaInvalidSubnetIDException"
+	ErrorReasonLambdaInvalidZipFileException              ErrorReason = "LambdaInvalidZipFileException"
+	ErrorReasonLambdaKMSAccessDeniedException             ErrorReason = "LambdaKMSAccessDeniedException"
+	ErrorReasonLambdaKMSDisabledException                 ErrorReason = "LambdaKMSDisabledException"
+	ErrorReasonLambdaKMSInvalidStateException             ErrorReason = "LambdaKMSInvalidStateException"
+	ErrorReasonLambdaKMSNotFoundException                 ErrorReason = "LambdaKMSNotFoundException"
+	ErrorReasonLambdaRequestTooLarge                      ErrorReason = "LambdaRequestTooLarge"
+	ErrorReasonLambdaResourceNotFound                     ErrorReason = "LambdaResourceNotFound"
+	ErrorReasonLambdaResponseTooLarge                     ErrorReason = "LambdaResponseTooLarge"
+	ErrorReasonLambdaServiceException                     ErrorReason = "LambdaServiceException"
+	ErrorReasonLambdaSubnetIPAddressLimitReachedException ErrorReason = "LambdaSubnetIPAddressLimitReachedException"
+	ErrorReasonLambdaThrottling                           ErrorReason = "LambdaThrottling"
+	ErrorReasonLambdaUnhandled                            ErrorReason = "LambdaUnhandled"
+	ErrorReasonLambdaUnhandledException                   ErrorReason = "LambdaUnhandledException"
+	ErrorReasonLambdaWebSocketNotSupported                ErrorReason = "LambdaWebSocketNotSupported"
+)
+
+var errorReasonDescriptions = map[ErrorReason]string{
+	ErrorReasonAuthInvalidCookie:                          "The authentication cookie is not valid.",
+	ErrorReasonAuthInvalidGrantError:                      "The authorization grant code from the token endpoint is not valid.",
+	ErrorReasonAuthInvalidIdToken:                         "The ID token is not valid.",
+	ErrorReasonAuthInvalidStateParam:                      "The state parameter is not valid.",
+	ErrorReasonAuthInvalidTokenResponse:                   "The response from the token endpoint is not valid.",
+	ErrorReasonAuthInvalidUserinfoResponse:                "The response from the user info endpoint is not valid.",
+	ErrorReasonAuthMissingCodeParam:                       "The authentication response from the IdP is missing a code query parameter.",
+	ErrorReasonAuthMissingHostHeader:                      "The authentication response from the IdP is missing a host header field.",
+	ErrorReasonAuthMissingStateParam:                      "The authentication response from the IdP is missing a state query parameter.",
+	ErrorReasonAuthTokenEpRequestFailed:                   "There is an error response (non-2XX) from the token endpoint.",
+	ErrorReasonAuthTokenEpRequestTimeout:                  "The load balancer is unable to communicate with the token endpoint, or the token endpoint is not responding within 5 seconds.",
+	ErrorReasonAuthUnhandledException:                     "The load balancer encountered an unhandled exception.",
+	ErrorReasonAuthUserinfoEpRequestFailed:                "There is an error response (non-2XX) from the IdP user info endpoint.",
+	ErrorReasonAuthUserinfoEpRequestTimeout:               "The load balancer is unable to communicate with the IdP user info endpoint, or the user info endpoint is not responding within 5 seconds.",
+	ErrorReasonAuthUserinfoResponseSizeExceeded:           "The size of the claims returned by the IdP exceeded 11K bytes.",
+	ErrorReasonLambdaAccessDenied:                         "The load balancer did not have permission to invoke the Lambda function.",
+	ErrorReasonLambdaBadRequest:                           "Lambda invocation failed because the client request headers or body did not contain only UTF-8 characters.",
+	ErrorReasonLambdaConnectionError:                      "The load balancer cannot connect to Lambda.",
+	ErrorReasonLambdaConnectionTimeout:                    "An attempt to connect to Lambda timed out.",
+	ErrorReasonLambdaEC2AccessDeniedException:             "Amazon EC2 denied access to Lambda during function initialization.",
+	ErrorReasonLambdaEC2ThrottledException:                "Amazon EC2 throttled Lambda during function initialization.",
+	ErrorReasonLambdaEC2UnexpectedException:               "Amazon EC2 encountered an unexpected exception during function initialization.",
+	ErrorReasonLambdaENILimitReachedException:             "Lambda couldn't create a network interface in the VPC specified in the configuration of the Lambda function because the limit for network interfaces was exceeded.",
+	ErrorReasonLambdaInvalidResponse:                      "The response from the Lambda function is malformed or is missing required fields.",
+	ErrorReasonLambdaInvalidRuntimeException:              "The specified version of the Lambda runtime is not supported.",
+	ErrorReasonLambdaInvalidSecurityGroupIDException:      "The security group ID specified in the configuration of the Lambda function is not valid.",
+	ErrorReasonLambdaInvalidSubnetIDException:             "The subnet ID specified in the configuration of the Lambda function is not valid.",
+	ErrorReasonLambdaInvalidZipFileException:              "Lambda could not unzip the specified function zip file.",
+	ErrorReasonLambdaKMSAccessDeniedException:             "Lambda could not decrypt environment variables because access to the KMS key was denied.",
+	ErrorReasonLambdaKMSDisabledException:                 "Lambda could not decrypt environment variables because the specified KMS key is disabled.",
+	ErrorReasonLambdaKMSInvalidStateException:             "Lambda could not decrypt environment variables because the state of the KMS key is not valid.",
+	ErrorReasonLambdaKMSNotFoundException:                 "Lambda could not decrypt environment variables because the KMS key was not found.",
+	ErrorReasonLambdaRequestTooLarge:                      "The size of the request body exceeded 1 MB.",
+	ErrorReasonLambdaResourceNotFound:                     "The Lambda function could not be found.",
+	ErrorReasonLambdaResponseTooLarge:                     "The size of the response exceeded 1 MB.",
+	ErrorReasonLambdaServiceException:                     "Lambda encountered an internal error.",
+	ErrorReasonLambdaSubnetIPAddressLimitReachedException: "Lambda could not set up VPC access for the Lambda function because one or more subnets have no available IP addresses.",
+	ErrorReasonLambdaThrottling:                           "The Lambda function was throttled because there were too many requests.",
+	ErrorReasonLambdaUnhandled:                            "The Lambda function encountered an unhandled exception.",
+	ErrorReasonLambdaUnhandledException:                   "The load balancer encountered an unhandled exception.",
+	ErrorReasonLambdaWebSocketNotSupported:                "WebSockets are not supported with Lambda.",
+}
+
+// Known returns true if r is one of the documented error reasons.
+func (r ErrorReason) Known() bool {
+	_, ok := errorReasonDescriptions[r]
+	return ok
+}
+
+// Normalize returns ErrorReasonNone if r is "-" or empty, r if it is known and ErrorReasonUnknown otherwise.
+func (r ErrorReason) Normalize() ErrorReason {
+	switch {
+	case isEmptyField(string(r)):
+		return ErrorReasonNone
+	case r.Known():
+		return r
+	default:
+		return ErrorReasonUnknown
+	}
+}
+
+// Description returns the description of r from AWS documentation, or an empty string if r is not known.
+func (r ErrorReason) Description() string {
+	return errorReasonDescriptions[r]
+}
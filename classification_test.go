@@ -0,0 +1,83 @@
+package elblog
+
+import (
+	"testing"
+)
+
+func TestClassification(t *testing.T) {
+	cases := map[string]struct {
+		given      Classification
+		known      bool
+		normalized Classification
+	}{
+		"acceptable": {given: "Acceptable", known: true, normalized: ClassificationAcceptable},
+		"severe":     {given: "Severe", known: true, normalized: ClassificationSevere},
+		"dash":       {given: "-", normalized: ClassificationNone},
+		"missing":    {given: "", normalized: ClassificationNone},
+		"new":        {given: "Suspicious", normalized: ClassificationUnknown},
+	}
+
+	for hint, c := range cases {
+		t.Run(hint, func(t *testing.T) {
+			if got := c.given.Known(); got != c.known {
+				t.Errorf("expected Known %t but got %t", c.known, got)
+			}
+			if got := c.given.Normalize(); got != c.normalized {
+				t.Errorf("expected %q but got %q", c.normalized, got)
+			}
+			if got := c.given.Description(); (got != "") != c.known {
+				t.Errorf("unexpected description %q", got)
+			}
+		})
+	}
+}
+
+func TestClassification_Description(t *testing.T) {
+	cases := map[Classification]string{
+		ClassificationAcceptable: "The request does not comply with RFC 7230 but poses no known security threats.",
+		ClassificationSevere:     "The request poses a high security risk. The load balancer blocks the request, serves a 400 response to the client, and closes the client connection.",
+		ClassificationNone:       "",
+	}
+
+	for given, expected := range cases {
+		t.Run(string(given), func(t *testing.T) {
+			if got := given.Description(); got != expected {
+				t.Errorf("expected %q but got %q", expected, got)
+			}
+		})
+	}
+}
+
+func TestErrorReason_Description(t *testing.T) {
+	if got, expected := ErrorReasonLambdaThrottling.Description(), "The Lambda function was throttled because there were too many requests."; got != expected {
+		t.Errorf("expected %q but got %q", expected, got)
+	}
+}
+
+func TestErrorReason(t *testing.T) {
+	cases := map[string]struct {
+		given      ErrorReason
+		known      bool
+		normalized ErrorReason
+	}{
+		"auth":    {given: "AuthInvalidCookie", known: true, normalized: ErrorReasonAuthInvalidCookie},
+		"new":     {given: "AuthNewReason", normalized: ErrorReasonUnknown},
+		"lambda":  {given: "LambdaConnectionTimeout", known: true, normalized: ErrorReasonLambdaConnectionTimeout},
+		"dash":    {given: "-", normalized: ErrorReasonNone},
+		"missing": {given: "", normalized: ErrorReasonNone},
+	}
+
+	for hint, c := range cases {
+		t.Run(hint, func(t *testing.T) {
+			if got := c.given.Known(); got != c.known {
+				t.Errorf("expected Known %t but got %t", c.known, got)
+			}
+			if got := c.given.Normalize(); got != c.normalized {
+				t.Errorf("expected %q but got %q", c.normalized, got)
+			}
+			if got := c.given.Description(); (got != "") != c.known {
+				t.Errorf("unexpected description %q", got)
+			}
+		})
+	}
+}
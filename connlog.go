@@ -0,0 +1,222 @@
+package elblog
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ConnectionLog is a single entry of ALB connection log. Connection logs describe TLS connections established
+// by clients, including the client certificate presented to listeners with mutual TLS enabled:
+// https://docs.aws.amazon.com/elasticloadbalancing/latest/application/load-balancer-connection-logs.html
+type ConnectionLog struct {
+	Time                   time.Time
+	From                   *net.TCPAddr
+	ListenerPort           int
+	TLSProtocol            string
+	TLSCipher              string
+	TLSHandshakeLatency    time.Duration
+	ClientCertSubject      string
+	ClientCertNotBefore    time.Time
+	ClientCertNotAfter     time.Time
+	ClientCertSerialNumber string
+	TLSVerifyStatus        string
+	ConnTraceID            string
+	OtherFields            string
+}
+
+// TLSVerifyStatusSuccess is logged if the client certificate was verified successfully.
+// Failures are logged as "Failed:" followed by the reason, e.g. "Failed:UntrustedCert".
+const TLSVerifyStatusSuccess = "Success"
+
+// Verified returns true if client certificate verification succeeded.
+func (c *ConnectionLog) Verified() bool {
+	return c.TLSVerifyStatus == TLSVerifyStatusSuccess
+}
+
+const numConnectionTokens = 13
+
+// connectionFieldNames are the names of connection log fields, as in AWS documentation.
+var connectionFieldNames = []string{
+	"timestamp", "client_ip", "client_port", "listener_port", "tls_protocol", "tls_cipher",
+	"tls_handshake_latency", "leaf_client_cert_subject", "leaf_client_cert_validity",
+	"leaf_client_cert_serial_number", "tls_verify_status", "conn_trace_id", "other_fields",
+}
+
+// ParseConnectionLog parses single line of ALB connection log.
+// Client certificate fields are left empty if client did not present a certificate.
+func ParseConnectionLog(b []byte) (*ConnectionLog, error) {
+	var (
+		adv, i int
+		port   uint64
+		sec    float64
+		err    error
+		tok    []byte
+	)
+
+	data := b
+	log := &ConnectionLog{}
+	for i < numConnectionTokens && adv < len(data) {
+		data = data[adv:]
+		adv, tok, err = scan(data)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan next token: %v", err)
+		}
+		switch i {
+		case 0:
+			log.Time, err = time.Parse(time.RFC3339Nano, string(tok))
+		case 1:
+			log.From = &net.TCPAddr{
+				IP: net.ParseIP(string(tok)),
+			}
+			if log.From.IP == nil {
+				err = fmt.Errorf("invalid ip address %q", tok)
+			}
+		case 2:
+			port, err = strconv.ParseUint(string(tok), 10, 16)
+			log.From.Port = int(port)
+		case 3:
+			port, err = strconv.ParseUint(string(tok), 10, 16)
+			log.ListenerPort = int(port)
+		case 4:
+			log.TLSProtocol = string(tok)
+		case 5:
+			log.TLSCipher = string(tok)
+		case 6:
+			sec, err = strconv.ParseFloat(string(tok), 64)
+			log.TLSHandshakeLatency = time.Duration(sec * 1000 * 1000 * 1000)
+		case 7:
+			if !isDash(tok) {
+				log.ClientCertSubject = string(tok)
+			}
+		case 8:
+			log.ClientCertNotBefore, log.ClientCertNotAfter, err = parseCertValidity(tok)
+		case 9:
+			if !isDash(tok) {
+				log.ClientCertSerialNumber = string(tok)
+			}
+		case 10:
+			log.TLSVerifyStatus = string(tok)
+		case 11:
+			if !isDash(tok) {
+				log.ConnTraceID = string(tok)
+			}
+		case 12:
+			// everything remaining goes into OtherFields, the same way as for access logs
+			log.OtherFields = string(data)
+			adv = len(data)
+		}
+		if err != nil {
+			return nil, newParseError(b, data, tok, i, connectionFieldNames, err)
+		}
+		i++
+	}
+	return log, nil
+}
+
+// parseCertValidity parses NotBefore=2023-09-21T22:43:21Z;NotAfter=2026-06-17T22:43:21Z token.
+func parseCertValidity(tok []byte) (notBefore, notAfter time.Time, err error) {
+	if isDash(tok) {
+		return
+	}
+	for _, part := range bytes.Split(tok, []byte(";")) {
+		key, value, ok := bytes.Cut(part, []byte("="))
+		if !ok {
+			return notBefore, notAfter, fmt.Errorf("missing '=' in %q", part)
+		}
+		switch string(key) {
+		case "NotBefore":
+			notBefore, err = time.Parse(time.RFC3339, string(value))
+		case "NotAfter":
+			notAfter, err = time.Parse(time.RFC3339, string(value))
+		}
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// ConnectionLogDecoder reads and parses ALB connection logs from an input stream.
+type ConnectionLogDecoder struct {
+	lines *lineScanner
+}
+
+// NewConnectionLogDecoder allocates new ConnectionLogDecoder object for given input.
+func NewConnectionLogDecoder(r io.Reader) *ConnectionLogDecoder {
+	return &ConnectionLogDecoder{
+		lines: newLineScanner(r),
+	}
+}
+
+// Decode scans input and parse into ConnectionLog. It returns EOF if there is nothing left to read.
+// Read errors are returned once, then Decode returns EOF.
+func (d *ConnectionLogDecoder) Decode() (*ConnectionLog, error) {
+	b, ok := d.lines.next()
+	if !ok {
+		if err := d.lines.readErr(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	return ParseConnectionLog(b)
+}
+
+// More return true if token is not empty, underlying scanner Scan method will return true
+// or there is a read error to return.
+func (d *ConnectionLogDecoder) More() bool {
+	return d.lines.more() || d.lines.failed()
+}
+
+// ConnectionIndex joins access log entries with connection log entries.
+// Requests are matched with the most recent connection established by the same client address (ip and port)
+// before the request was logged. The zero value is not usable, use NewConnectionIndex.
+type ConnectionIndex struct {
+	conns map[string][]*ConnectionLog
+}
+
+// NewConnectionIndex allocates new ConnectionIndex with given connection log entries.
+func NewConnectionIndex(conns ...*ConnectionLog) *ConnectionIndex {
+	idx := &ConnectionIndex{
+		conns: make(map[string][]*ConnectionLog),
+	}
+	for _, c := range conns {
+		idx.Add(c)
+	}
+	return idx
+}
+
+// Add adds connection log entry to the index. Entries can be added in any order.
+func (idx *ConnectionIndex) Add(c *ConnectionLog) {
+	if c.From == nil {
+		return
+	}
+	key := c.From.String()
+	conns := idx.conns[key]
+	i := sort.Search(len(conns), func(i int) bool {
+		return conns[i].Time.After(c.Time)
+	})
+	conns = append(conns, nil)
+	copy(conns[i+1:], conns[i:])
+	conns[i] = c
+	idx.conns[key] = conns
+}
+
+// Lookup returns connection log entry of the connection given request was sent over, or nil if there is none.
+func (idx *ConnectionIndex) Lookup(l *Log) *ConnectionLog {
+	if l.From == nil {
+		return nil
+	}
+	conns := idx.conns[l.From.String()]
+	i := sort.Search(len(conns), func(i int) bool {
+		return conns[i].Time.After(l.Time)
+	})
+	if i == 0 {
+		return nil
+	}
+	return conns[i-1]
+}
@@ -0,0 +1,185 @@
+package elblog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseConnectionLog(t *testing.T) {
+	cases := map[string]struct {
+		given    string
+		expected ConnectionLog
+	}{
+		"mtls": {
+			given: `2023-10-04T17:55:09.564436Z 192.0.2.1 45678 443 TLSv1.2 ECDHE-RSA-AES128-GCM-SHA256 0.004 "CN=amazondomains.com,O=endEntity,L=Seattle,ST=Washington,C=US" NotBefore=2023-09-21T22:43:21Z;NotAfter=2026-06-17T22:43:21Z FEF257E8D2C9A9AE Success TID_1c3c3f4f8e1e9c4b8a5d3e2f1a0b9c8d`,
+			expected: ConnectionLog{
+				Time: time.Date(2023, 10, 4, 17, 55, 9, 564436000, time.UTC),
+				From: &net.TCPAddr{
+					IP:   net.ParseIP("192.0.2.1"),
+					Port: 45678,
+				},
+				ListenerPort:           443,
+				TLSProtocol:            "TLSv1.2",
+				TLSCipher:              "ECDHE-RSA-AES128-GCM-SHA256",
+				TLSHandshakeLatency:    4 * time.Millisecond,
+				ClientCertSubject:      "CN=amazondomains.com,O=endEntity,L=Seattle,ST=Washington,C=US",
+				ClientCertNotBefore:    time.Date(2023, 9, 21, 22, 43, 21, 0, time.UTC),
+				ClientCertNotAfter:     time.Date(2026, 6, 17, 22, 43, 21, 0, time.UTC),
+				ClientCertSerialNumber: "FEF257E8D2C9A9AE",
+				TLSVerifyStatus:        "Success",
+				ConnTraceID:            "TID_1c3c3f4f8e1e9c4b8a5d3e2f1a0b9c8d",
+			},
+		},
+		"no-client-cert": {
+			given: `2023-10-04T17:55:09.564436Z 2001:db8::1 45678 443 TLSv1.3 TLS_AES_128_GCM_SHA256 0.002 - - - Failed:ClientCertMissing`,
+			expected: ConnectionLog{
+				Time: time.Date(2023, 10, 4, 17, 55, 9, 564436000, time.UTC),
+				From: &net.TCPAddr{
+					IP:   net.ParseIP("2001:db8::1"),
+					Port: 45678,
+				},
+				ListenerPort:        443,
+				TLSProtocol:         "TLSv1.3",
+				TLSCipher:           "TLS_AES_128_GCM_SHA256",
+				TLSHandshakeLatency: 2 * time.Millisecond,
+				TLSVerifyStatus:     "Failed:ClientCertMissing",
+			},
+		},
+	}
+
+	for hint, c := range cases {
+		t.Run(hint, func(t *testing.T) {
+			got, err := ParseConnectionLog([]byte(c.given))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			if !reflect.DeepEqual(*got, c.expected) {
+				t.Errorf("expected:\n	%v but got:\n	%v", c.expected, *got)
+			}
+		})
+	}
+}
+
+func TestParseConnectionLog_invalid(t *testing.T) {
+	cases := map[string]string{
+		"ip":       `2023-10-04T17:55:09.564436Z localhost 45678 443 TLSv1.2 ECDHE-RSA-AES128-GCM-SHA256 0.004`,
+		"port":     `2023-10-04T17:55:09.564436Z 192.0.2.1 -1 443 TLSv1.2 ECDHE-RSA-AES128-GCM-SHA256 0.004`,
+		"validity": `2023-10-04T17:55:09.564436Z 192.0.2.1 45678 443 TLSv1.2 ECDHE-RSA-AES128-GCM-SHA256 0.004 "CN=a" NotBefore;NotAfter FEF257E8D2C9A9AE Success`,
+	}
+
+	for hint, given := range cases {
+		t.Run(hint, func(t *testing.T) {
+			if _, err := ParseConnectionLog([]byte(given)); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestConnectionLogDecoder_Decode(t *testing.T) {
+	input := `2023-10-04T17:55:09.564436Z 192.0.2.1 45678 443 TLSv1.2 ECDHE-RSA-AES128-GCM-SHA256 0.004 "CN=amazondomains.com,O=endEntity,L=Seattle,ST=Washington,C=US" NotBefore=2023-09-21T22:43:21Z;NotAfter=2026-06-17T22:43:21Z FEF257E8D2C9A9AE Success
+2023-10-04T17:55:10.564436Z 192.0.2.2 45679 443 TLSv1.2 ECDHE-RSA-AES128-GCM-SHA256 0.004 - - - Failed:UntrustedCert
+`
+	dec := NewConnectionLogDecoder(bytes.NewBufferString(input))
+	var verified []bool
+	for dec.More() {
+		log, err := dec.Decode()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		verified = append(verified, log.Verified())
+	}
+	if expected := []bool{true, false}; !reflect.DeepEqual(verified, expected) {
+		t.Errorf("expected %v but got %v", expected, verified)
+	}
+}
+
+func TestConnectionLogDecoder_Decode_readError(t *testing.T) {
+	line := `2023-10-04T17:55:10.564436Z 192.0.2.2 45679 443 TLSv1.2 ECDHE-RSA-AES128-GCM-SHA256 0.004 - - - Failed:UntrustedCert`
+	buf := bytes.NewBuffer(nil)
+	zw := gzip.NewWriter(buf)
+	if _, err := io.WriteString(zw, strings.Repeat(line+"\n", 50)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	truncated := buf.Bytes()[:buf.Len()-10]
+
+	dec := NewConnectionLogDecoder(Decompress(bytes.NewReader(truncated)))
+	var n int
+	var got error
+	for dec.More() {
+		_, err := dec.Decode()
+		if err != nil {
+			got = err
+			continue
+		}
+		n++
+	}
+	if got != io.ErrUnexpectedEOF {
+		t.Errorf("expected ErrUnexpectedEOF after %d logs, got: %v", n, got)
+	}
+	if _, err := dec.Decode(); err != io.EOF {
+		t.Errorf("expected EOF after the read error, got: %v", err)
+	}
+}
+
+func TestConnectionIndex_Lookup(t *testing.T) {
+	at := func(sec int) time.Time {
+		return time.Date(2023, 10, 4, 17, 55, sec, 0, time.UTC)
+	}
+	client := &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 45678}
+	first := &ConnectionLog{Time: at(1), From: client, TLSVerifyStatus: "Success"}
+	second := &ConnectionLog{Time: at(30), From: client, TLSVerifyStatus: "Failed:CertExpired"}
+	other := &ConnectionLog{Time: at(2), From: &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 45679}}
+
+	// added out of order on purpose
+	idx := NewConnectionIndex(second, other, first)
+
+	cases := map[string]struct {
+		given    *Log
+		expected *ConnectionLog
+	}{
+		"before-any-connection": {
+			given: &Log{Time: at(0), From: client},
+		},
+		"first-connection": {
+			given:    &Log{Time: at(10), From: client},
+			expected: first,
+		},
+		"same-time": {
+			given:    &Log{Time: at(30), From: client},
+			expected: second,
+		},
+		"second-connection": {
+			given:    &Log{Time: at(40), From: client},
+			expected: second,
+		},
+		"different-port": {
+			given:    &Log{Time: at(40), From: &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 45679}},
+			expected: other,
+		},
+		"unknown-client": {
+			given: &Log{Time: at(40), From: &net.TCPAddr{IP: net.ParseIP("192.0.2.2"), Port: 45678}},
+		},
+		"no-client": {
+			given: &Log{Time: at(40)},
+		},
+	}
+
+	for hint, c := range cases {
+		t.Run(hint, func(t *testing.T) {
+			if got := idx.Lookup(c.given); got != c.expected {
+				t.Errorf("expected %v but got %v", c.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package elblog
+
+import (
+	"context"
+	"io"
+	"iter"
+)
+
+// DecodeContext works like Decode, but it returns ctx.Err() once ctx is done. Cancellation is checked before every line
+// and it aborts a read that is in progress, e.g. from a slow network stream. An aborted read is a read error, so
+// nothing can be decoded afterwards and the goroutine doing it exits only once the underlying Read returns.
+// If the context is done before anything is read, Decoder can be used further.
+func (d *Decoder) DecodeContext(ctx context.Context) (*Log, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if d.hasPending {
+		return d.Decode()
+	}
+	d.lines.withContext(ctx)
+	defer d.lines.withContext(nil)
+	return d.Decode()
+}
+
+// AllContext works like All, but it decodes logs using DecodeContext. Iteration stops after ctx.Err() is yielded.
+func (d *Decoder) AllContext(ctx context.Context) iter.Seq2[*Log, error] {
+	return func(yield func(*Log, error) bool) {
+		for {
+			log, err := d.DecodeContext(ctx)
+			if err == io.EOF {
+				return
+			}
+			if !yield(log, err) || (err != nil && err == ctx.Err()) {
+				return
+			}
+		}
+	}
+}
+
+// withContext sets the context of reads, nil makes them not abortable.
+func (l *lineScanner) withContext(ctx context.Context) {
+	l.setup()
+	l.cr.ctx = ctx
+}
+
+// ctxReader is a reader whose reads can be aborted by a context. Reads with a context are done in a separate goroutine,
+// to a buffer of the reader, so the goroutine does not write to the caller's buffer after a read is aborted.
+type ctxReader struct {
+	r   io.Reader
+	ctx context.Context
+	buf []byte
+}
+
+func (c *ctxReader) Read(p []byte) (int, error) {
+	if c.ctx == nil || c.ctx.Done() == nil {
+		return c.r.Read(p)
+	}
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if cap(c.buf) < len(p) {
+		c.buf = make([]byte, len(p))
+	}
+	buf := c.buf[:len(p)]
+
+	type result struct {
+		n   int
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		n, err := c.r.Read(buf)
+		done <- result{n: n, err: err}
+	}()
+	select {
+	case res := <-done:
+		return copy(p, buf[:res.n]), res.err
+	case <-c.ctx.Done():
+		// the buffer is still in use
+		c.buf = nil
+		return 0, c.ctx.Err()
+	}
+}
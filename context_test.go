@@ -0,0 +1,109 @@
+package elblog
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecoder_DecodeContext(t *testing.T) {
+	t.Run("slow-reader", func(t *testing.T) {
+		r, w := io.Pipe()
+		defer w.Close()
+		go func() {
+			_, _ = io.WriteString(w, validLine+"\n")
+		}()
+
+		dec := NewDecoder(r)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if _, err := dec.DecodeContext(ctx); err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		start := time.Now()
+		if _, err := dec.DecodeContext(ctx); err != context.DeadlineExceeded {
+			t.Fatalf("expected DeadlineExceeded, got: %v", err)
+		}
+		if time.Since(start) > time.Second {
+			t.Error("read should be aborted")
+		}
+		if _, err := dec.Decode(); err != io.EOF {
+			t.Errorf("expected EOF after aborted read, got: %v", err)
+		}
+	})
+	t.Run("canceled-mid-line", func(t *testing.T) {
+		r, w := io.Pipe()
+		defer w.Close()
+		go func() {
+			_, _ = io.WriteString(w, validLine+"\n"+validLine[:len(validLine)/2])
+		}()
+
+		dec := NewDecoder(r)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if _, err := dec.DecodeContext(ctx); err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		if log, err := dec.DecodeContext(ctx); err != context.DeadlineExceeded {
+			t.Fatalf("expected DeadlineExceeded instead of the partial line, got: %v, %v", log, err)
+		}
+		if dec.Line() != 1 || dec.Offset() != int64(len(validLine)+1) {
+			t.Errorf("position should stay after the first line, got %d, %d", dec.Line(), dec.Offset())
+		}
+		if _, err := dec.Decode(); err != io.EOF {
+			t.Errorf("expected EOF after aborted read, got: %v", err)
+		}
+	})
+	t.Run("canceled-between-lines", func(t *testing.T) {
+		dec := NewDecoder(strings.NewReader(validLine + "\n" + validLine))
+		ctx, cancel := context.WithCancel(context.Background())
+		if _, err := dec.DecodeContext(ctx); err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		cancel()
+		if _, err := dec.DecodeContext(ctx); err != context.Canceled {
+			t.Fatalf("expected Canceled, got: %v", err)
+		}
+		if _, err := dec.Decode(); err != nil {
+			t.Fatalf("line should not be consumed, got: %v", err)
+		}
+		if dec.Line() != 2 {
+			t.Errorf("wrong line, expected 2 but got %d", dec.Line())
+		}
+	})
+}
+
+func TestDecoder_AllContext(t *testing.T) {
+	input := strings.Repeat(validLine+"\n", 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dec := NewDecoder(strings.NewReader(input))
+	var n int
+	var last error
+	for _, err := range dec.AllContext(ctx) {
+		last = err
+		if err != nil {
+			continue
+		}
+		if n++; n == 3 {
+			cancel()
+		}
+	}
+	if n != 3 || last != context.Canceled {
+		t.Errorf("iteration should stop after cancellation, got %d logs and error: %v", n, last)
+	}
+
+	n = 0
+	for _, err := range NewDecoder(strings.NewReader(input)).AllContext(context.Background()) {
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		n++
+	}
+	if n != 10 {
+		t.Errorf("wrong number of decoded logs, expected 10 but got %d", n)
+	}
+}
@@ -0,0 +1,445 @@
+package elblog
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+)
+
+// ErrorPolicy tells Decoder what to do with lines that cannot be parsed.
+type ErrorPolicy int
+
+const (
+	// ErrorPolicyFail makes Decode return the error. Decoding can be continued with the next line. It is the default.
+	ErrorPolicyFail ErrorPolicy = iota
+	// ErrorPolicySkip makes Decode skip malformed lines.
+	ErrorPolicySkip
+	// ErrorPolicyCollect makes Decode skip malformed lines and keep them, so they can be retrieved using Skipped.
+	ErrorPolicyCollect
+)
+
+// LongLinePolicy tells Decoder what to do with lines longer than the maximum line size, see WithMaxLineSize.
+type LongLinePolicy int
+
+const (
+	// LongLineFail makes Decode return bufio.ErrTooLong and stop, as nothing can be read after an oversized line.
+	// It is the default.
+	LongLineFail LongLinePolicy = iota
+	// LongLineTruncate makes Decoder parse the beginning of an oversized line, up to the maximum line size.
+	LongLineTruncate
+	// LongLineReport makes Decoder treat oversized lines as malformed, they are reported with ErrLineTooLong
+	// according to the error policy. Decoding continues with the next line.
+	LongLineReport
+)
+
+// ErrLineTooLong is reported for lines longer than the maximum line size if LongLineReport is set.
+var ErrLineTooLong = errors.New("elblog: line too long")
+
+// errFiltered is returned by parseLine for logs not matching the filter, they are skipped without being reported.
+var errFiltered = errors.New("elblog: log filtered out")
+
+// SkippedLine is a line skipped by Decoder because it could not be parsed.
+type SkippedLine struct {
+	// Line is the number of the line, starting at 1.
+	Line int
+	// Offset is the byte offset at which the line starts.
+	Offset int64
+	// Data is the content of the line.
+	Data []byte
+	// Err is the reason why the line was skipped.
+	Err error
+}
+
+// Decoder ...
+type Decoder struct {
+	lines   *lineScanner
+	format  Format
+	opts    ParseOptions
+	policy  ErrorPolicy
+	onSkip  func(SkippedLine)
+	skipped []SkippedLine
+	filter  *Filter
+	// unordered is used by ParallelDecoder only.
+	unordered bool
+
+	// pending is the result decoded by More, it is returned by the next Decode call.
+	pending    decoded
+	hasPending bool
+	line       int
+	offset     int64
+}
+
+type decoded struct {
+	log    *Log
+	err    error
+	line   int
+	offset int64
+}
+
+// DecoderOption configures a Decoder.
+type DecoderOption func(*Decoder)
+
+// WithFormat sets the format of decoded logs. By default Decoder expects ALB logs.
+// FormatAuto makes it possible to decode input that mixes ALB and Classic Load Balancer logs.
+func WithFormat(f Format) DecoderOption {
+	return func(d *Decoder) {
+		d.format = f
+	}
+}
+
+// WithParseOptions sets options of parsing, e.g. ModeStrict. NoCopy is ignored, as lines are read to a reused buffer.
+func WithParseOptions(o ParseOptions) DecoderOption {
+	return func(d *Decoder) {
+		d.opts = o
+	}
+}
+
+// WithErrorPolicy sets what Decoder does with lines that cannot be parsed. By default it is ErrorPolicyFail.
+func WithErrorPolicy(p ErrorPolicy) DecoderOption {
+	return func(d *Decoder) {
+		d.policy = p
+	}
+}
+
+// WithSkipHandler sets function called for every line skipped because of ErrorPolicySkip or ErrorPolicyCollect,
+// e.g. to quarantine bad records.
+func WithSkipHandler(fn func(SkippedLine)) DecoderOption {
+	return func(d *Decoder) {
+		d.onSkip = fn
+	}
+}
+
+// WithMaxLineSize sets the maximum length of a line, without the line terminator. By default it is bufio.MaxScanTokenSize,
+// which is kept if n is not positive.
+func WithMaxLineSize(n int) DecoderOption {
+	return func(d *Decoder) {
+		if n > 0 {
+			d.lines.maxLine = n
+		}
+	}
+}
+
+// WithLongLinePolicy sets what Decoder does with lines longer than the maximum line size. By default it is LongLineFail.
+func WithLongLinePolicy(p LongLinePolicy) DecoderOption {
+	return func(d *Decoder) {
+		d.lines.longLines = p
+	}
+}
+
+// WithGzip makes Decoder decompress gzip input, see Decompress. Uncompressed input is decoded as usual.
+// Line offsets reported by Decoder refer to the decompressed input.
+func WithGzip() DecoderOption {
+	return func(d *Decoder) {
+		d.lines.decompress = true
+	}
+}
+
+// NewDecoder allocates new Decoder object for given input.
+func NewDecoder(r io.Reader, opts ...DecoderOption) *Decoder {
+	d := &Decoder{
+		lines: newLineScanner(r),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Decode scans input and parse into Log. It can return EOF if underlying scanner Scan method returns false.
+// Parse errors are returned as *ParseError with Line set. Read errors are returned once, then Decode returns EOF.
+func (d *Decoder) Decode() (*Log, error) {
+	res := d.pending
+	if d.hasPending {
+		d.pending, d.hasPending = decoded{}, false
+	} else {
+		res = d.decode()
+	}
+	d.line, d.offset = res.line, res.offset
+	return res.log, res.err
+}
+
+// All returns an iterator over logs decoded from the input, it calls Decode until it returns EOF.
+// Errors are yielded the same way Decode returns them, so iteration continues after a malformed line unless the loop breaks.
+// Breaking out of the loop leaves Decoder at the next line, decoding can be resumed.
+func (d *Decoder) All() iter.Seq2[*Log, error] {
+	return func(yield func(*Log, error) bool) {
+		for {
+			log, err := d.Decode()
+			if err == io.EOF {
+				return
+			}
+			if !yield(log, err) {
+				return
+			}
+		}
+	}
+}
+
+// All returns an iterator over logs decoded from r, see Decoder.All.
+func All(r io.Reader, opts ...DecoderOption) iter.Seq2[*Log, error] {
+	return NewDecoder(r, opts...).All()
+}
+
+// More return true if there is anything left to decode. Lines skipped because of the error policy are not taken into account.
+func (d *Decoder) More() bool {
+	if !d.hasPending {
+		d.pending, d.hasPending = d.decode(), true
+	}
+	return d.pending.err != io.EOF
+}
+
+// Line returns the number of the line last returned by Decode, starting at 1.
+func (d *Decoder) Line() int {
+	return d.line
+}
+
+// Offset returns the byte offset right after the line last returned by Decode.
+func (d *Decoder) Offset() int64 {
+	return d.offset
+}
+
+// Skipped returns lines skipped so far. It is populated only if ErrorPolicyCollect is set.
+func (d *Decoder) Skipped() []SkippedLine {
+	return d.skipped
+}
+
+func (d *Decoder) decode() decoded {
+	for {
+		b, ok := d.lines.next()
+		if !ok {
+			err := d.lines.readErr()
+			if err == nil {
+				err = io.EOF
+			}
+			return decoded{err: err, line: d.lines.line, offset: d.lines.offset}
+		}
+		log, err := d.parseLine(b, d.lines.line, d.lines.oversized, false)
+		if err == errFiltered {
+			continue
+		}
+		if d.skipLine(b, d.lines.line, d.lines.start, err) {
+			continue
+		}
+		return decoded{log: log, err: err, line: d.lines.line, offset: d.lines.offset}
+	}
+}
+
+// parseLine parses line number line, oversized lines are reported according to the long line policy.
+// Logs refer to the memory of the line if noCopy is true.
+func (d *Decoder) parseLine(b []byte, line int, oversized bool, noCopy bool) (*Log, error) {
+	if oversized && d.lines.longLines == LongLineReport {
+		return nil, fmt.Errorf("line %d: %w", line, ErrLineTooLong)
+	}
+	opts := d.opts
+	opts.NoCopy = noCopy
+	log, err := d.parse(b, opts)
+	var perr *ParseError
+	if errors.As(err, &perr) {
+		perr.Line = line
+	}
+	if err == nil && d.filter != nil && !d.filter.Match(log) {
+		return nil, errFiltered
+	}
+	return log, err
+}
+
+// skipLine returns true if the line is skipped because of the error policy.
+func (d *Decoder) skipLine(b []byte, line int, start int64, err error) bool {
+	if err == nil || d.policy == ErrorPolicyFail {
+		return false
+	}
+	d.skip(SkippedLine{
+		Line:   line,
+		Offset: start,
+		Data:   bytes.Clone(b),
+		Err:    err,
+	})
+	return true
+}
+
+func (d *Decoder) skip(s SkippedLine) {
+	if d.policy == ErrorPolicyCollect {
+		d.skipped = append(d.skipped, s)
+	}
+	if d.onSkip != nil {
+		d.onSkip(s)
+	}
+}
+
+func (d *Decoder) parse(b []byte, opts ParseOptions) (*Log, error) {
+	format := d.format
+	if format == FormatAuto {
+		format = DetectFormat(b)
+	}
+	log := &Log{}
+	if err := parse(b, log, format, opts); err != nil {
+		return nil, err
+	}
+	return log, nil
+}
+
+// lineScanner reads input line by line, it is shared by all decoders.
+// It keeps track of the number and the byte offset of the lines it returns.
+type lineScanner struct {
+	r     io.Reader
+	s     *bufio.Scanner
+	token []byte
+	err   error
+
+	// decompress is true if the input may be gzip compressed.
+	decompress bool
+	// cr makes reads abortable, see Decoder.DecodeContext.
+	cr *ctxReader
+
+	// maxLine is the maximum length of a line, without the line terminator.
+	maxLine   int
+	longLines LongLinePolicy
+	// discard is true if the rest of an oversized line is being skipped, truncated holds its beginning.
+	// oversized is true if the last scanned line was longer than maxLine, unterminated is true if it did not end
+	// with a line terminator.
+	discard      bool
+	truncated    []byte
+	oversized    bool
+	unterminated bool
+
+	// scanned is the number of bytes consumed by the scanner, lines is the number of lines it returned.
+	scanned int64
+	lines   int
+	// line is the number of the line last returned by next, start and offset are the offsets
+	// at which it starts and right after it ends.
+	line          int
+	start, offset int64
+}
+
+func newLineScanner(r io.Reader) *lineScanner {
+	return &lineScanner{
+		r:       r,
+		maxLine: bufio.MaxScanTokenSize,
+	}
+}
+
+func (l *lineScanner) split(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	advance, token, err = l.splitLine(data, atEOF)
+	l.scanned += int64(advance)
+	return advance, token, err
+}
+
+func (l *lineScanner) splitLine(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if l.discard {
+		// the truncated line is returned once the rest of it is consumed, so offsets point right after it
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 && !atEOF {
+			return len(data), nil, nil
+		}
+		advance = len(data)
+		if i >= 0 {
+			advance = i + 1
+		}
+		l.discard, l.oversized, l.unterminated = false, true, i < 0
+		return advance, l.truncated, nil
+	}
+	// the line terminator may be \r\n, so the scanner buffer holds one byte more than maxLine before \n
+	i := bytes.IndexByte(data, '\n')
+	var discard bool
+	switch {
+	case i > l.maxLine && !(i == l.maxLine+1 && data[i-1] == '\r'):
+		advance = i + 1
+	case i < 0 && len(data) > l.maxLine+1:
+		// the line does not fit into the buffer, the rest of it is discarded
+		advance, discard = len(data), true
+	case i < 0 && atEOF && len(bytes.TrimSuffix(data, []byte("\r"))) > l.maxLine:
+		advance = len(data)
+	}
+	if advance > 0 {
+		if l.longLines == LongLineFail {
+			return 0, nil, bufio.ErrTooLong
+		}
+		if discard {
+			l.truncated = append(l.truncated[:0], data[:l.maxLine]...)
+			l.discard = true
+			return advance, nil, nil
+		}
+		l.oversized, l.unterminated = true, i < 0
+		return advance, data[:l.maxLine], nil
+	}
+	advance, token, err = bufio.ScanLines(data, atEOF)
+	if token != nil {
+		l.oversized, l.unterminated = false, advance == 0 || data[advance-1] != '\n'
+	}
+	return advance, token, err
+}
+
+// setup creates the scanner on first use, once decoder options are applied.
+func (l *lineScanner) setup() {
+	if l.s != nil {
+		return
+	}
+	if l.decompress {
+		l.r = Decompress(l.r)
+	}
+	l.cr = &ctxReader{r: l.r}
+	l.s = bufio.NewScanner(l.cr)
+	// two extra bytes for the \r\n line terminator
+	l.s.Buffer(make([]byte, 0, min(4096, l.maxLine+2)), l.maxLine+2)
+	l.s.Split(l.split)
+}
+
+func (l *lineScanner) scan() bool {
+	l.setup()
+	// Scanner that failed would return the buffered rest of the input as if it reached EOF.
+	if l.s.Err() != nil || !l.s.Scan() {
+		return false
+	}
+	// a line cut off by a read error is incomplete, the error is reported instead
+	if l.s.Err() != nil && l.unterminated {
+		return false
+	}
+	l.lines++
+	return true
+}
+
+// next returns the line scanned by more or scans a new one. It returns false if there is nothing left to read.
+func (l *lineScanner) next() ([]byte, bool) {
+	b := l.token
+	if b != nil {
+		l.token = nil
+	} else {
+		if !l.scan() {
+			return nil, false
+		}
+		b = l.s.Bytes()
+	}
+	l.line, l.start, l.offset = l.lines, l.offset, l.scanned
+	return b, true
+}
+
+// more return true if token is not empty or underlying scanner Scan method will return true.
+func (l *lineScanner) more() bool {
+	if l.token != nil {
+		return true
+	}
+
+	ok := l.scan()
+	if ok {
+		l.token = l.s.Bytes()
+	}
+	return ok
+}
+
+// readErr returns the error that stopped the scanner, e.g. bufio.ErrTooLong.
+// It returns the error only once, so a decoder reports it a single time and then returns io.EOF.
+func (l *lineScanner) readErr() error {
+	if !l.failed() {
+		return nil
+	}
+	l.err = l.s.Err()
+	return l.err
+}
+
+// failed returns true if the scanner stopped on an error that was not returned by readErr yet.
+func (l *lineScanner) failed() bool {
+	return l.s != nil && l.s.Err() != nil && l.err == nil
+}
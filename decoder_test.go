@@ -0,0 +1,317 @@
+package elblog
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+const (
+	validLine   = `http 2015-05-13T23:39:43.945958Z my-loadbalancer 192.168.131.39:2817 10.0.0.1:80 0.000073 0.001048 0.000057 200 200 0 29 "GET http://www.example.com:80/ HTTP/1.1" "curl/7.38.0" - -`
+	invalidLine = `http 2015-05-13T23:39:43.945958Z my-loadbalancer 192.168.131.39:2817 10.0.0.1:80 0.000073 0.001048 0.000057 OK 200 0 29 "GET http://www.example.com:80/ HTTP/1.1" "curl/7.38.0" - -`
+)
+
+func TestDecoder_Decode_errorPolicy(t *testing.T) {
+	input := strings.Join([]string{validLine, invalidLine, validLine, invalidLine, ""}, "\n")
+	pair := int64(len(validLine) + len(invalidLine) + 2)
+
+	t.Run("fail", func(t *testing.T) {
+		dec := NewDecoder(strings.NewReader(input))
+		var lines []int
+		for dec.More() {
+			_, err := dec.Decode()
+			if err != nil {
+				var perr *ParseError
+				if !errors.As(err, &perr) {
+					t.Fatalf("expected ParseError but got: %v", err)
+				}
+				if perr.Line != dec.Line() {
+					t.Errorf("wrong line, expected %d but got %d", dec.Line(), perr.Line)
+				}
+				lines = append(lines, perr.Line)
+			}
+		}
+		if expected := []int{2, 4}; !reflect.DeepEqual(lines, expected) {
+			t.Errorf("expected errors at lines %v but got %v", expected, lines)
+		}
+	})
+	t.Run("skip", func(t *testing.T) {
+		var skipped []SkippedLine
+		dec := NewDecoder(strings.NewReader(input), WithErrorPolicy(ErrorPolicySkip), WithSkipHandler(func(s SkippedLine) {
+			skipped = append(skipped, s)
+		}))
+		n := 0
+		for dec.More() {
+			if _, err := dec.Decode(); err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+			n++
+		}
+		if n != 2 {
+			t.Errorf("wrong number of decoded logs, expected 2 but got %d", n)
+		}
+		if len(dec.Skipped()) != 0 {
+			t.Errorf("skipped lines should not be collected, got %d", len(dec.Skipped()))
+		}
+		if len(skipped) != 2 {
+			t.Fatalf("wrong number of skipped lines, expected 2 but got %d", len(skipped))
+		}
+		if skipped[1].Line != 4 || skipped[1].Offset != pair+int64(len(validLine)+1) {
+			t.Errorf("wrong position of skipped line: %d, %d", skipped[1].Line, skipped[1].Offset)
+		}
+	})
+	t.Run("collect", func(t *testing.T) {
+		dec := NewDecoder(strings.NewReader(input), WithErrorPolicy(ErrorPolicyCollect))
+		for dec.More() {
+			if _, err := dec.Decode(); err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+		}
+		if _, err := dec.Decode(); err != io.EOF {
+			t.Errorf("expected EOF, got: %v", err)
+		}
+		skipped := dec.Skipped()
+		if len(skipped) != 2 {
+			t.Fatalf("wrong number of skipped lines, expected 2 but got %d", len(skipped))
+		}
+		for i, s := range skipped {
+			if s.Line != 2+2*i || s.Offset != int64(i)*pair+int64(len(validLine)+1) {
+				t.Errorf("wrong position of skipped line %d: %d, %d", i, s.Line, s.Offset)
+			}
+			if string(s.Data) != invalidLine {
+				t.Errorf("wrong data of skipped line %d: %s", i, s.Data)
+			}
+			var perr *ParseError
+			if !errors.As(s.Err, &perr) || perr.Field != "elb_status_code" {
+				t.Errorf("wrong error of skipped line %d: %v", i, s.Err)
+			}
+		}
+	})
+}
+
+func TestDecoder_Decode_readError(t *testing.T) {
+	connReset := errors.New("connection reset")
+	for hint, policy := range map[string]ErrorPolicy{"fail": ErrorPolicyFail, "skip": ErrorPolicySkip} {
+		t.Run(hint, func(t *testing.T) {
+			r := io.MultiReader(strings.NewReader(validLine+"\n"+validLine[:100]), iotest.ErrReader(connReset))
+			dec := NewDecoder(r, WithErrorPolicy(policy))
+			if _, err := dec.Decode(); err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+			if log, err := dec.Decode(); err != connReset {
+				t.Fatalf("expected read error instead of the partial line, got: %v, %v", log, err)
+			}
+			if _, err := dec.Decode(); err != io.EOF {
+				t.Errorf("expected EOF after the read error, got: %v", err)
+			}
+			if dec.Line() != 1 || len(dec.Skipped()) != 0 {
+				t.Errorf("partial line should not be decoded nor skipped, got line %d and %d skipped lines", dec.Line(), len(dec.Skipped()))
+			}
+		})
+	}
+}
+
+func TestDecoder_Line(t *testing.T) {
+	input := validLine + "\r\n" + invalidLine + "\n" + validLine + "\n" + validLine
+	dec := NewDecoder(strings.NewReader(input), WithErrorPolicy(ErrorPolicySkip))
+
+	expected := []struct {
+		line   int
+		offset int64
+	}{
+		{line: 1, offset: int64(len(validLine) + 2)},
+		{line: 3, offset: int64(2*len(validLine) + len(invalidLine) + 4)},
+		{line: 4, offset: int64(3*len(validLine) + len(invalidLine) + 4)},
+	}
+	for i, e := range expected {
+		if !dec.More() {
+			t.Fatalf("missing log %d", i)
+		}
+		if i > 0 && dec.Line() != expected[i-1].line {
+			t.Errorf("More should not change the line, expected %d but got %d", expected[i-1].line, dec.Line())
+		}
+		if _, err := dec.Decode(); err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		if dec.Line() != e.line || dec.Offset() != e.offset {
+			t.Errorf("wrong position of log %d, expected %d, %d but got %d, %d", i, e.line, e.offset, dec.Line(), dec.Offset())
+		}
+	}
+	if dec.More() {
+		t.Error("there should be nothing left")
+	}
+}
+
+func TestDecoder_Decode_longLines(t *testing.T) {
+	maxLine := len(validLine)
+	long := strings.Replace(validLine, "curl/7.38.0", strings.Repeat("x", 1000), 1)
+	input := strings.Join([]string{validLine, long, validLine + "\r", ""}, "\n")
+
+	t.Run("fail", func(t *testing.T) {
+		dec := NewDecoder(strings.NewReader(input), WithMaxLineSize(maxLine))
+		if _, err := dec.Decode(); err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		if _, err := dec.Decode(); err != bufio.ErrTooLong {
+			t.Fatalf("expected ErrTooLong, got: %v", err)
+		}
+		if dec.More() {
+			t.Error("nothing can be decoded after an oversized line")
+		}
+		if _, err := dec.Decode(); err != io.EOF {
+			t.Errorf("expected EOF, got: %v", err)
+		}
+	})
+	t.Run("truncate", func(t *testing.T) {
+		dec := NewDecoder(strings.NewReader(input), WithMaxLineSize(maxLine), WithLongLinePolicy(LongLineTruncate))
+		var logs []*Log
+		for dec.More() {
+			log, err := dec.Decode()
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+			logs = append(logs, log)
+		}
+		if len(logs) != 3 {
+			t.Fatalf("wrong number of decoded logs, expected 3 but got %d", len(logs))
+		}
+		if logs[1].Request != "GET http://www.example.com:80/ HTTP/1.1" {
+			t.Errorf("wrong request of truncated line: %s", logs[1].Request)
+		}
+		if logs[1].SSLCipher != "" {
+			t.Errorf("fields after the limit should be empty, got: %s", logs[1].SSLCipher)
+		}
+		if logs[2].UserAgent != "curl/7.38.0" {
+			t.Errorf("wrong user agent of the line after truncated one: %s", logs[2].UserAgent)
+		}
+		if dec.Line() != 3 || dec.Offset() != int64(len(input)) {
+			t.Errorf("wrong position: %d, %d", dec.Line(), dec.Offset())
+		}
+	})
+	t.Run("report", func(t *testing.T) {
+		dec := NewDecoder(strings.NewReader(input), WithMaxLineSize(maxLine), WithLongLinePolicy(LongLineReport), WithErrorPolicy(ErrorPolicyCollect))
+		n := 0
+		for dec.More() {
+			if _, err := dec.Decode(); err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+			n++
+		}
+		if n != 2 {
+			t.Errorf("wrong number of decoded logs, expected 2 but got %d", n)
+		}
+		skipped := dec.Skipped()
+		if len(skipped) != 1 {
+			t.Fatalf("wrong number of skipped lines, expected 1 but got %d", len(skipped))
+		}
+		if skipped[0].Line != 2 || skipped[0].Offset != int64(len(validLine)+1) || !errors.Is(skipped[0].Err, ErrLineTooLong) {
+			t.Errorf("wrong skipped line: %d, %d, %v", skipped[0].Line, skipped[0].Offset, skipped[0].Err)
+		}
+		if len(skipped[0].Data) != maxLine {
+			t.Errorf("skipped data should be truncated to %d bytes, got %d", maxLine, len(skipped[0].Data))
+		}
+	})
+	t.Run("one-byte-over", func(t *testing.T) {
+		over := validLine + " \n" + validLine
+		dec := NewDecoder(strings.NewReader(over), WithMaxLineSize(maxLine))
+		if _, err := dec.Decode(); err != bufio.ErrTooLong {
+			t.Errorf("expected ErrTooLong, got: %v", err)
+		}
+		dec = NewDecoder(strings.NewReader(over), WithMaxLineSize(maxLine), WithLongLinePolicy(LongLineReport), WithErrorPolicy(ErrorPolicyCollect))
+		if _, err := dec.Decode(); err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		if dec.Line() != 2 || len(dec.Skipped()) != 1 {
+			t.Errorf("the first line should be skipped, got line %d and %d skipped lines", dec.Line(), len(dec.Skipped()))
+		}
+	})
+	t.Run("not-positive", func(t *testing.T) {
+		for _, n := range []int{0, -1, -5} {
+			dec := NewDecoder(strings.NewReader(long), WithMaxLineSize(n), WithLongLinePolicy(LongLineTruncate))
+			if _, err := dec.Decode(); err != nil {
+				t.Errorf("default maximum line size should be kept for %d, got: %v", n, err)
+			}
+		}
+	})
+	t.Run("report-last-line", func(t *testing.T) {
+		dec := NewDecoder(strings.NewReader(validLine+"\n"+long), WithMaxLineSize(maxLine), WithLongLinePolicy(LongLineReport))
+		if _, err := dec.Decode(); err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		if _, err := dec.Decode(); !errors.Is(err, ErrLineTooLong) {
+			t.Fatalf("expected ErrLineTooLong, got: %v", err)
+		}
+		if _, err := dec.Decode(); err != io.EOF {
+			t.Errorf("expected EOF, got: %v", err)
+		}
+	})
+}
+
+func TestDecoder_All(t *testing.T) {
+	input := strings.Join([]string{validLine, invalidLine, validLine, validLine}, "\n")
+	dec := NewDecoder(strings.NewReader(input))
+
+	var errs int
+	for log, err := range dec.All() {
+		if err != nil {
+			errs++
+			continue
+		}
+		if log.UserAgent != "curl/7.38.0" {
+			t.Errorf("wrong user agent: %s", log.UserAgent)
+		}
+		if dec.Line() == 3 {
+			break
+		}
+	}
+	if errs != 1 {
+		t.Errorf("wrong number of errors, expected 1 but got %d", errs)
+	}
+	if _, err := dec.Decode(); err != nil {
+		t.Fatalf("decoding should be resumed after break, got: %v", err)
+	}
+	if dec.Line() != 4 {
+		t.Errorf("wrong line, expected 4 but got %d", dec.Line())
+	}
+	for range dec.All() {
+		t.Error("nothing should be left")
+	}
+}
+
+func TestAll(t *testing.T) {
+	input := strings.Join([]string{validLine, invalidLine, validLine}, "\n")
+	var n int
+	for _, err := range All(strings.NewReader(input), WithErrorPolicy(ErrorPolicySkip)) {
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		n++
+	}
+	if n != 2 {
+		t.Errorf("wrong number of decoded logs, expected 2 but got %d", n)
+	}
+}
+
+func TestDecoder_Decode_strict(t *testing.T) {
+	truncated := validLine[:strings.Index(validLine, ` "curl`)]
+	input := strings.Join([]string{validLine, truncated, validLine}, "\n")
+	dec := NewDecoder(strings.NewReader(input), WithParseOptions(ParseOptions{Mode: ModeStrict, NoCopy: true}), WithErrorPolicy(ErrorPolicyCollect))
+	var logs []*Log
+	for log, err := range dec.All() {
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		logs = append(logs, log)
+	}
+	if len(logs) != 2 {
+		t.Errorf("wrong number of decoded logs, expected 2 but got %d", len(logs))
+	}
+	skipped := dec.Skipped()
+	if len(skipped) != 1 || skipped[0].Line != 2 || !errors.Is(skipped[0].Err, ErrFieldCount) {
+		t.Fatalf("truncated line should be skipped, got: %v", skipped)
+	}
+}
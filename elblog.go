@@ -1,14 +1,16 @@
 package elblog
 
 import (
-	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
-	"io"
 	"net"
+	"net/netip"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
-	"unicode/utf8"
+	"unsafe"
 )
 
 // Log ...
@@ -17,14 +19,15 @@ type Log struct {
 	Time                   time.Time
 	Name                   string
 	From, To               *net.TCPAddr
-	RequestProcessingTime  time.Duration
-	BackendProcessingTime  time.Duration
-	ResponseProcessingTime time.Duration
+	RequestProcessingTime  NullDuration
+	BackendProcessingTime  NullDuration
+	ResponseProcessingTime NullDuration
 	ELBStatusCode          int
-	BackendStatusCode      string
+	BackendStatusCode      NullInt
 	ReceivedBytes          int64
 	SentBytes              int64
 	Request                string
+	RequestLine            RequestLine
 	UserAgent              string
 	SSLCipher              string
 	SSLProtocol            string
@@ -36,220 +39,665 @@ type Log struct {
 	RequestCreationTime    string
 	ActionsExecuted        string
 	RedirectURL            string
-	ErrorReason            string
+	ErrorReason            ErrorReason
 	TargetPortList         string
 	TargetStatusCodeList   string
-	Classification         string
+	Classification         Classification
 	ClassificationReason   string
 	OtherFields            string
+	// TrailingFields are known fields added after classification_reason, parsed from OtherFields by their names,
+	// see ParseOptions.TrailingFields. It is nil if there are none.
+	TrailingFields map[string]string
+	// UnknownFields is the raw rest of OtherFields that follows the known trailing fields.
+	UnknownFields string
+	// Version is the format version detected by the number of fields.
+	Version Version
 }
 
-const numTokens = 30
+// NullDuration is a duration that may be not available. Load balancer logs -1 as processing time
+// if it could not dispatch the request to a target, or if the target closed the connection before sending a response.
+type NullDuration struct {
+	Duration time.Duration
+	Valid    bool // Valid is true if Duration is available
+}
+
+// NullInt is an integer that may be not available, e.g. status code of a target that did not respond.
+type NullInt struct {
+	Int   int
+	Valid bool // Valid is true if Int is available
+}
+
+const (
+	numTokens        = 30
+	numClassicTokens = 16
+)
+
+// fieldNames are the names of ALB log fields, as in AWS documentation.
+var fieldNames = []string{
+	"type", "time", "elb", "client:port", "target:port",
+	"request_processing_time", "target_processing_time", "response_processing_time",
+	"elb_status_code", "target_status_code", "received_bytes", "sent_bytes",
+	"request", "user_agent", "ssl_cipher", "ssl_protocol", "target_group_arn", "trace_id",
+	"domain_name", "chosen_cert_arn", "matched_rule_priority", "request_creation_time",
+	"actions_executed", "redirect_url", "error_reason", "target:port_list", "target_status_code_list",
+	"classification", "classification_reason", "other_fields",
+}
+
+// DefaultTrailingFields are the names of fields added by AWS after classification_reason, in order.
+var DefaultTrailingFields = []string{"conn_trace_id", "transformed_host", "transformed_uri", "request_transform_status"}
+
+// classicFieldNames are the names of Classic Load Balancer log fields, as in AWS documentation.
+var classicFieldNames = []string{
+	"timestamp", "elb", "client:port", "backend:port",
+	"request_processing_time", "backend_processing_time", "response_processing_time",
+	"elb_status_code", "backend_status_code", "received_bytes", "sent_bytes",
+	"request", "user_agent", "ssl_cipher", "ssl_protocol",
+}
+
+// ParseError is returned if a field of a log entry is invalid.
+type ParseError struct {
+	// Line is the number of the line, starting at 1. It is set only by decoders, Parse leaves it 0.
+	Line int
+	// Index of the field within the line, starting at 0.
+	Index int
+	// Field is the name of the field as in AWS documentation, e.g. "elb_status_code".
+	Field string
+	// Token is the raw value of the field.
+	Token string
+	// Offset is the byte offset of the field within the line.
+	Offset int
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("line %d: invalid field %q at index %d (%s, offset %d): %v", e.Line, e.Token, e.Index, e.Field, e.Offset, e.Err)
+	}
+	return fmt.Sprintf("invalid field %q at index %d (%s, offset %d): %v", e.Token, e.Index, e.Field, e.Offset, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// newParseError creates ParseError for token scanned from data, which is the remaining part of the line b.
+func newParseError(b, data, tok []byte, index int, names []string, err error) *ParseError {
+	offset := len(b) - len(data)
+	for offset < len(b) && b[offset] == ' ' {
+		offset++
+	}
+	e := &ParseError{
+		Index:  index,
+		Token:  string(tok),
+		Offset: offset,
+		Err:    err,
+	}
+	if index < len(names) {
+		e.Field = names[index]
+	}
+	return e
+}
+
+// Format is a load balancer access log format.
+type Format int
+
+const (
+	// FormatALB is the Application Load Balancer access log format.
+	FormatALB Format = iota
+	// FormatClassic is the Classic Load Balancer access log format.
+	FormatClassic
+	// FormatAuto detects the format of every line separately, see DetectFormat.
+	FormatAuto
+)
+
+// DetectFormat returns the format of given line. Classic Load Balancer logs start with a timestamp,
+// whereas ALB logs start with a request type (http, https, h2, ws, wss, ...).
+func DetectFormat(b []byte) Format {
+	b = bytes.TrimLeft(b, " ")
+	if len(b) > 0 && b[0] >= '0' && b[0] <= '9' {
+		return FormatClassic
+	}
+	return FormatALB
+}
+
+// Parse parses single line of ALB access log.
+func Parse(b []byte) (*Log, error) {
+	return ParseOptions{}.Parse(b)
+}
 
-// Parse ...
-func Parse(b []byte) (log *Log, err error) {
+// ParseInto parses single line of ALB access log into log, see ParseOptions.ParseInto.
+func ParseInto(b []byte, log *Log) error {
+	return ParseOptions{}.ParseInto(b, log)
+}
+
+// Mode tells how strictly lines are validated.
+type Mode int
+
+const (
+	// ModeLenient accepts any number of fields, missing trailing fields are left empty. It is the default.
+	ModeLenient Mode = iota
+	// ModeStrict accepts only lines with a number of fields of one of the known format versions, see Version,
+	// so truncated lines are not mistaken for older logs. Unterminated quoted fields are rejected as well.
+	ModeStrict
+)
+
+var (
+	// ErrFieldCount is reported in ModeStrict if a line has a number of fields that does not match any format version.
+	ErrFieldCount = errors.New("elblog: unexpected number of fields")
+	// ErrUnterminatedQuote is reported in ModeStrict if a quoted field is not terminated.
+	ErrUnterminatedQuote = errors.New("elblog: unterminated quoted field")
+)
+
+// ParseOptions configures parsing of ALB and Classic Load Balancer access logs. The zero value is what Parse uses.
+type ParseOptions struct {
+	// Mode tells how strictly lines are validated, ModeLenient by default.
+	Mode Mode
+	// TrailingFields are the names of fields that follow classification_reason, in order. Values of the fields
+	// present in a line are put into Log.TrailingFields, the rest into Log.UnknownFields. OtherFields is kept intact.
+	// If it is nil, DefaultTrailingFields are used. An empty slice makes all the fields unknown.
+	TrailingFields []string
+	// NoCopy makes string fields of Log refer to the memory of the parsed line instead of a copy of it.
+	// It saves an allocation per line, but the line must not be modified for as long as the Log is in use,
+	// so it cannot be used with lines returned by bufio.Scanner, which reuses its buffer. Decoder ignores it.
+	NoCopy bool
+}
+
+// Parse parses single line of ALB access log.
+func (o ParseOptions) Parse(b []byte) (*Log, error) {
+	log := &Log{}
+	if err := o.ParseInto(b, log); err != nil {
+		return nil, err
+	}
+	return log, nil
+}
+
+// ParseInto parses single line of ALB access log into log, which is reset first.
+// Memory of the From and To addresses is reused, so they must not be retained between calls.
+// All string fields are sliced from a single copy of the line. The only other allocations are made
+// for the parsed request URL and for quoted fields containing escape sequences.
+// Memory of TrailingFields map is reused as well. The content of log is undefined if an error is returned.
+func (o ParseOptions) ParseInto(b []byte, log *Log) error {
+	return parse(b, log, FormatALB, o)
+}
+
+// ParseClassic parses single line of Classic Load Balancer access log.
+// Classic logs do not contain a request type, so Type is left empty.
+// Fields introduced by ALB (TargetGroupARN and the following ones) are left empty as well.
+func ParseClassic(b []byte) (*Log, error) {
+	return ParseOptions{}.ParseClassic(b)
+}
+
+// ParseClassic parses single line of Classic Load Balancer access log, see ParseClassic.
+// In ModeStrict all 15 fields are required.
+func (o ParseOptions) ParseClassic(b []byte) (*Log, error) {
+	log := &Log{}
+	if err := parse(b, log, FormatClassic, o); err != nil {
+		return nil, err
+	}
+	return log, nil
+}
+
+// parse scans tokens of b into the fields of log. Switch cases are indexes of ALB fields,
+// classic format has the same fields but it has no type, so it starts at index 1.
+func parse(b []byte, log *Log, format Format, opts ParseOptions) (err error) {
 	var (
-		adv, i int
-		code   int64
-		dur    float64
-		ip     int64
-		tok    []byte
-		parts  [][]byte
+		adv  int
+		code int64
+		tok  []byte
 	)
 
-	data := b[adv:]
-	log = &Log{}
-	i = 0
-	for i < numTokens && adv < len(data) {
+	from, to, trailing := log.From, log.To, log.TrailingFields
+	*log = Log{}
+	str := newLineStrings(b, opts.NoCopy)
+
+	i, n, names := 0, numTokens, fieldNames
+	if format == FormatClassic {
+		i, n, names = 1, numClassicTokens, classicFieldNames
+	}
+	first := i
+	data := b
+	for i < n && adv < len(data) {
 		data = data[adv:]
 		adv, tok, err = scan(data)
 		if err != nil {
-			return nil, fmt.Errorf("unable to scan next token: %v", err)
+			return fmt.Errorf("unable to scan next token: %v", err)
 		}
 		switch i {
 		case 0:
-			log.Type = string(tok)
+			log.Type = str.get(tok)
 		case 1:
 			log.Time, err = time.Parse(time.RFC3339Nano, string(tok))
 		case 2:
-			log.Name = string(tok)
+			log.Name = str.get(tok)
 		case 3:
-			parts = bytes.Split(tok, []byte(":"))
-			switch len(parts) {
-			case 1:
-				log.From = &net.TCPAddr{
-					IP: net.ParseIP(string(parts[0])),
-				}
-			case 2:
-				ip, err = strconv.ParseInt(string(parts[1]), 10, 32)
-				log.From = &net.TCPAddr{
-					IP:   net.ParseIP(string(parts[0])),
-					Port: int(ip),
-				}
-			}
+			log.From, err = parseAddr(tok, from)
 		case 4:
-			parts = bytes.Split(tok, []byte(":"))
-			switch len(parts) {
-			case 1:
-				log.To = &net.TCPAddr{
-					IP: net.ParseIP(string(parts[0])),
-				}
-			case 2:
-				ip, err = strconv.ParseInt(string(parts[1]), 10, 32)
-				log.To = &net.TCPAddr{
-					IP:   net.ParseIP(string(parts[0])),
-					Port: int(ip),
-				}
-			}
+			log.To, err = parseAddr(tok, to)
 		case 5:
-			dur, err = strconv.ParseFloat(string(tok), 64)
-			log.RequestProcessingTime = time.Duration(dur * 1000 * 1000 * 1000)
+			log.RequestProcessingTime, err = parseProcessingTime(tok)
 		case 6:
-			dur, err = strconv.ParseFloat(string(tok), 64)
-			log.BackendProcessingTime = time.Duration(dur * 1000 * 1000 * 1000)
+			log.BackendProcessingTime, err = parseProcessingTime(tok)
 		case 7:
-			dur, err = strconv.ParseFloat(string(tok), 64)
-			log.ResponseProcessingTime = time.Duration(dur * 1000 * 1000 * 1000)
+			log.ResponseProcessingTime, err = parseProcessingTime(tok)
 		case 8:
-			code, err = strconv.ParseInt(string(tok), 10, 32)
-			log.ELBStatusCode = int(code)
+			// "-" is logged by classic load balancer for TCP and SSL listeners.
+			if !isDash(tok) {
+				code, err = strconv.ParseInt(string(tok), 10, 32)
+				log.ELBStatusCode = int(code)
+			}
 		case 9:
-			log.BackendStatusCode = string(tok)
+			log.BackendStatusCode, err = parseStatusCode(tok)
 		case 10:
-			log.ReceivedBytes, err = strconv.ParseInt(string(tok), 10, 32)
+			log.ReceivedBytes, err = strconv.ParseInt(string(tok), 10, 64)
 		case 11:
-			log.SentBytes, err = strconv.ParseInt(string(tok), 10, 32)
+			log.SentBytes, err = strconv.ParseInt(string(tok), 10, 64)
 		case 12:
-			log.Request = string(tok)
+			log.Request = str.get(tok)
+			log.RequestLine = ParseRequestLine(log.Request)
 		case 13:
-			log.UserAgent = string(tok)
+			log.UserAgent = str.get(tok)
 		case 14:
-			log.SSLCipher = string(tok)
+			log.SSLCipher = str.get(tok)
 		case 15:
-			log.SSLProtocol = string(tok)
+			log.SSLProtocol = str.get(tok)
 		case 16:
-			log.TargetGroupARN = string(tok)
+			log.TargetGroupARN = str.get(tok)
 		case 17:
-			log.TraceID = string(tok)
+			log.TraceID = str.get(tok)
 		case 18:
-			log.DomainName = string(tok)
+			log.DomainName = str.get(tok)
 		case 19:
-			log.ChosenCertARN = string(tok)
+			log.ChosenCertARN = str.get(tok)
 		case 20:
-			log.MatchedRulePriority = string(tok)
+			log.MatchedRulePriority = str.get(tok)
 		case 21:
-			log.RequestCreationTime = string(tok)
+			log.RequestCreationTime = str.get(tok)
 		case 22:
-			log.ActionsExecuted = string(tok)
+			log.ActionsExecuted = str.get(tok)
 		case 23:
-			log.RedirectURL = string(tok)
+			log.RedirectURL = str.get(tok)
 		case 24:
-			log.ErrorReason = string(tok)
+			log.ErrorReason = ErrorReason(str.get(tok))
 		case 25:
-			log.TargetPortList = string(tok)
+			log.TargetPortList = str.get(tok)
 		case 26:
-			log.TargetStatusCodeList = string(tok)
+			log.TargetStatusCodeList = str.get(tok)
 		case 27:
-			log.Classification = string(tok)
+			log.Classification = Classification(str.get(tok))
 		case 28:
-			log.ClassificationReason = string(tok)
+			log.ClassificationReason = str.get(tok)
 		case 29:
 			// we've scanned one token but we want to put everything remaining into OtherFields
 			// (including the spaces and quotes)
-			log.OtherFields = string(data)
+			log.OtherFields = str.get(data)
 			adv = len(data)
+			log.TrailingFields, log.UnknownFields = parseTrailingFields(data, str, opts.trailingFields(), trailing)
+		}
+		if err == nil && opts.Mode == ModeStrict && adv == len(data) && unterminated(data) {
+			err = ErrUnterminatedQuote
 		}
 		if err != nil {
-			return nil, fmt.Errorf("invalid field %q at index %d: %v", tok, i, err)
+			return newParseError(b, data, tok, i-first, names, err)
 		}
 		i++
 	}
-	return
+	fields := i - first
+	// classic lines have no OtherFields, the remaining fields are counted
+	for rest := data[adv:]; len(bytes.TrimLeft(rest, " ")) > 0; fields++ {
+		adv, _, _ = scan(rest)
+		rest = rest[adv:]
+	}
+	log.Version = detectVersion(fields, format)
+	if opts.Mode == ModeStrict && log.Version == VersionUnknown {
+		return newParseError(b, b[len(b):], nil, i-first, names, fmt.Errorf("%w, got %d", ErrFieldCount, fields))
+	}
+	return nil
 }
 
-// scan works like bufio.ScanWord (most of the code is taken from there),
-// but treat everything between quotation marks also as a word.
-func scan(data []byte) (advance int, token []byte, err error) {
-	// Skip leading spaces.
-	start := 0
-	open := false
-	trim := false
-	for width := 0; start < len(data); start += width {
-		var r rune
-		r, width = utf8.DecodeRune(data[start:])
-		if r != ' ' {
+// trailingFields returns names of the trailing fields.
+func (o ParseOptions) trailingFields() []string {
+	if o.TrailingFields == nil {
+		return DefaultTrailingFields
+	}
+	return o.TrailingFields
+}
+
+// parseTrailingFields parses known fields at the beginning of data, it returns the unknown rest as well.
+// Map m is reused if it is not nil.
+func parseTrailingFields(data []byte, str lineStrings, names []string, m map[string]string) (map[string]string, string) {
+	data = bytes.TrimLeft(data, " ")
+	if len(names) == 0 || len(data) == 0 {
+		return nil, str.get(data)
+	}
+	if m == nil {
+		m = make(map[string]string, len(names))
+	} else {
+		clear(m)
+	}
+	for _, name := range names {
+		if len(data) == 0 {
 			break
 		}
+		adv, tok, _ := scan(data)
+		m[name] = str.get(tok)
+		data = bytes.TrimLeft(data[adv:], " ")
 	}
-	// Scan until space, marking end of word.
-	for width, i := 0, start; i < len(data); i += width {
-		var r rune
-		r, width = utf8.DecodeRune(data[i:])
-		if r == '"' {
-			trim = true
-			open = !open
+	return m, str.get(data)
+}
 
-		}
-		if r == ' ' && !open {
-			if trim {
-				return i + width, data[start+1 : i-1], nil
-			}
-			return i + width, data[start:i], nil
+// unterminated returns true if the last token of data is a quoted one that is not terminated,
+// data has to start at a token boundary.
+func unterminated(data []byte) bool {
+	open, escaped := false, false
+	for _, c := range data {
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\' && open:
+			escaped = true
+		case c == '"':
+			open = !open
 		}
 	}
-	// We have a final, non-empty, non-terminated word. Return it.
-	if len(data) > start {
-		if trim && !open {
-			return len(data), data[start+1 : len(data)-1], nil
+	return open
+}
+
+// lineStrings converts tokens of a line into strings. Tokens that are part of the line are sliced
+// from a single copy of it, or from the line itself if copying is disabled.
+type lineStrings struct {
+	b []byte
+	s string
+}
+
+func newLineStrings(b []byte, noCopy bool) lineStrings {
+	if noCopy {
+		return lineStrings{b: b, s: unsafe.String(unsafe.SliceData(b), len(b))}
+	}
+	return lineStrings{b: b, s: string(b)}
+}
+
+func (ls lineStrings) get(tok []byte) string {
+	if len(tok) == 0 {
+		return ""
+	}
+	// tokens scanned from the line share its memory, so the offset can be derived from the capacity
+	if off := cap(ls.b) - cap(tok); off >= 0 && off+len(tok) <= len(ls.b) && &ls.b[off] == &tok[0] {
+		return ls.s[off : off+len(tok)]
+	}
+	// unescaped token
+	return string(tok)
+}
+
+// parseProcessingTime parses processing time in seconds. -1 means that the time is not available.
+func parseProcessingTime(tok []byte) (NullDuration, error) {
+	if string(tok) == "-1" {
+		return NullDuration{}, nil
+	}
+	d, err := parseSeconds(tok)
+	if err != nil {
+		return NullDuration{}, err
+	}
+	return NullDuration{Duration: d, Valid: true}, nil
+}
+
+// parseSeconds parses number of seconds. Decimal notation (e.g. 0.000073) is parsed exactly, up to nanoseconds,
+// other notations are parsed as floating point numbers.
+func parseSeconds(tok []byte) (time.Duration, error) {
+	digits, neg := tok, false
+	if len(digits) > 0 && digits[0] == '-' {
+		digits, neg = digits[1:], true
+	}
+	sec, frac, dot := bytes.Cut(digits, []byte("."))
+	if len(sec) > 9 || !isDigits(sec) || (dot && !isDigits(frac)) {
+		f, err := strconv.ParseFloat(string(tok), 64)
+		return time.Duration(f * 1000 * 1000 * 1000), err
+	}
+	var d time.Duration
+	for _, c := range sec {
+		d = d*10 + time.Duration(c-'0')
+	}
+	for i := range 9 {
+		d *= 10
+		if i < len(frac) {
+			d += time.Duration(frac[i] - '0')
 		}
-		return len(data), data[start:], nil
 	}
-	// Request more data.
-	return start, nil, nil
+	if neg {
+		d = -d
+	}
+	return d, nil
 }
 
-// Decoder ...
-type Decoder struct {
-	s     *bufio.Scanner
-	token []byte
+// parseStatusCode parses status code, "-" means that there is none.
+func parseStatusCode(tok []byte) (NullInt, error) {
+	if isDash(tok) {
+		return NullInt{}, nil
+	}
+	code, err := strconv.ParseInt(string(tok), 10, 32)
+	if err != nil {
+		return NullInt{}, err
+	}
+	return NullInt{Int: int(code), Valid: true}, nil
 }
 
-// NewDecoder allocates new Decoder object for given input.
-func NewDecoder(r io.Reader) *Decoder {
-	s := bufio.NewScanner(r)
-	s.Split(bufio.ScanLines)
+func isDash(tok []byte) bool {
+	return len(tok) == 1 && tok[0] == '-'
+}
+
+// RequestLine is the request line of a Log split into its parts.
+type RequestLine struct {
+	Method string
+	// RequestURI is the unmodified request target, as logged by the load balancer.
+	RequestURI string
+	// URL is nil if RequestURI could not be parsed.
+	URL        *url.URL
+	Proto      string // "HTTP/1.1"
+	ProtoMajor int    // 1
+	ProtoMinor int    // 1
+}
+
+// ParseRequestLine splits request line (e.g. "GET http://www.example.com:80/ HTTP/1.1") into its parts.
+// It never fails. Parts that are "-" are left empty, so the "- - - " request logged for TCP and SSL listeners,
+// as well as any line that does not consist of at least three parts, results in the zero value.
+func ParseRequestLine(s string) RequestLine {
+	s = strings.TrimRight(s, " ")
+	first, last := strings.IndexByte(s, ' '), strings.LastIndexByte(s, ' ')
+	if first < 0 || first == last {
+		return RequestLine{}
+	}
 
-	return &Decoder{
-		s: s,
+	var req RequestLine
+	if method := s[:first]; method != "-" {
+		req.Method = method
 	}
+	// malformed request targets may contain spaces
+	if uri := s[first+1 : last]; uri != "-" {
+		req.RequestURI = uri
+		if u, err := url.Parse(uri); err == nil {
+			req.URL = u
+		}
+	}
+	if proto := s[last+1:]; proto != "-" {
+		req.Proto = proto
+		req.ProtoMajor, req.ProtoMinor, _ = parseHTTPVersion(proto)
+	}
+	return req
 }
 
-// Decode scans input and parse into Log. It can return EOF if underlying scanner Scan method returns false.
-func (d *Decoder) Decode() (*Log, error) {
-	if d.token != nil {
-		log, err := Parse(d.token)
-		d.token = nil
+// parseHTTPVersion parses "HTTP/major.minor" protocol version, it works like http.ParseHTTPVersion.
+func parseHTTPVersion(proto string) (major, minor int, ok bool) {
+	version, ok := strings.CutPrefix(proto, "HTTP/")
+	if !ok {
+		return 0, 0, false
+	}
+	majorStr, minorStr, ok := strings.Cut(version, ".")
+	if !ok {
+		return 0, 0, false
+	}
+	major, err := strconv.Atoi(majorStr)
+	if err != nil || major < 0 {
+		return 0, 0, false
+	}
+	minor, err = strconv.Atoi(minorStr)
+	if err != nil || minor < 0 {
+		return 0, 0, false
+	}
+	return major, minor, true
+}
+
+// parseAddr parses ip:port token. IPv6 addresses are accepted both in bracketed ([::1]:80)
+// and unbracketed (::1:80) form. A port is optional. It returns nil address if token is "-",
+// which is what load balancer writes if request was not forwarded to any target (e.g. Lambda
+// or fixed-response). If addr is not nil, its memory is reused.
+func parseAddr(tok []byte, addr *net.TCPAddr) (*net.TCPAddr, error) {
+	if len(tok) == 0 || isDash(tok) {
+		return nil, nil
+	}
+	host, port, err := splitHostPort(tok)
+	if err != nil {
+		return nil, err
+	}
+	ip, ok := parseIP(host)
+	if !ok {
+		return nil, fmt.Errorf("invalid ip address %q", host)
+	}
+	if addr == nil {
+		// a single allocation for both the address and the ip
+		s := &struct {
+			addr net.TCPAddr
+			ip   [net.IPv6len]byte
+		}{}
+		addr = &s.addr
+		addr.IP = s.ip[:0]
+	}
+	// 16-byte form, the same as returned by net.ParseIP
+	ip16 := ip.As16()
+	*addr = net.TCPAddr{
+		IP: append(addr.IP[:0], ip16[:]...),
+	}
+	if port != nil {
+		p, err := strconv.ParseUint(string(port), 10, 16)
 		if err != nil {
 			return nil, err
 		}
-		return log, nil
+		addr.Port = int(p)
 	}
-	ok := d.s.Scan()
-	if !ok {
-		return nil, io.EOF
+	return addr, nil
+}
+
+// parseIP parses IPv4 or IPv6 address without a zone, like net.ParseIP does, but without allocating.
+func parseIP(b []byte) (netip.Addr, bool) {
+	// the string does not outlive the call, errors are discarded
+	ip, err := netip.ParseAddr(unsafe.String(unsafe.SliceData(b), len(b)))
+	if err != nil || ip.Zone() != "" {
+		return netip.Addr{}, false
+	}
+	return ip, true
+}
+
+// splitHostPort splits token into host and port. Port is nil if token does not contain one.
+func splitHostPort(tok []byte) (host, port []byte, err error) {
+	if tok[0] == '[' {
+		end := bytes.IndexByte(tok, ']')
+		if end < 0 {
+			return nil, nil, fmt.Errorf("missing ']' in address %q", tok)
+		}
+		host = tok[1:end]
+		switch rest := tok[end+1:]; {
+		case len(rest) == 0:
+			return host, nil, nil
+		case rest[0] == ':':
+			return host, rest[1:], nil
+		default:
+			return nil, nil, fmt.Errorf("unexpected %q after ']' in address %q", rest, tok)
+		}
+	}
+
+	i := bytes.LastIndexByte(tok, ':')
+	switch {
+	case i < 0:
+		return tok, nil, nil
+	case bytes.IndexByte(tok[:i], ':') < 0:
+		// ipv4:port
+		return tok[:i], tok[i+1:], nil
 	}
-	return Parse(d.s.Bytes())
+	// Unbracketed IPv6. Load balancer always logs a port, so the last group is treated as one,
+	// unless what precedes it is not a valid address (e.g. "2001:db8::").
+	if _, ok := parseIP(tok[:i]); ok && isDigits(tok[i+1:]) {
+		return tok[:i], tok[i+1:], nil
+	}
+	return tok, nil, nil
 }
 
-// More return true if token is not empty or underlying scanner Scan method will return true.
-func (d *Decoder) More() bool {
-	if d.token != nil {
-		return true
+func isDigits(b []byte) bool {
+	if len(b) == 0 {
+		return false
+	}
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return false
+		}
 	}
+	return true
+}
 
-	ok := d.s.Scan()
-	if ok {
-		d.token = d.s.Bytes()
+// scan works like bufio.ScanWord (most of the code is taken from there),
+// but treat everything between quotation marks also as a word.
+// Between quotation marks a backslash escapes the next character, so \" does not end the word.
+// Escaped quotation marks and backslashes are unescaped in the returned token.
+func scan(data []byte) (advance int, token []byte, err error) {
+	// Skip leading spaces.
+	start := 0
+	open := false
+	escaped := false
+	escapes := false
+	for start < len(data) && data[start] == ' ' {
+		start++
+	}
+	// Scan until space, marking end of word.
+	// Space, quotation mark and backslash are ASCII, so there is no need to decode runes.
+	for i := start; i < len(data); i++ {
+		switch c := data[i]; {
+		case escaped:
+			escaped = false
+		case c == '\\' && open:
+			escaped = true
+			escapes = true
+		case c == '"':
+			open = !open
+		case c == ' ' && !open:
+			return i + 1, unquote(data[start:i], escapes), nil
+		}
+	}
+	// We have a final, non-empty, non-terminated word. Return it.
+	if len(data) > start {
+		if open {
+			return len(data), data[start:], nil
+		}
+		return len(data), unquote(data[start:], escapes), nil
+	}
+	// Request more data.
+	return start, nil, nil
+}
+
+// unquote removes quotation marks around the token, and unescapes its content if it contains escape sequences.
+func unquote(tok []byte, escapes bool) []byte {
+	if len(tok) < 2 || tok[0] != '"' || tok[len(tok)-1] != '"' {
+		return tok
+	}
+	tok = tok[1 : len(tok)-1]
+	if !escapes {
+		return tok
+	}
+	// Only escaped quotation marks and backslashes are unescaped,
+	// other sequences (e.g. \x00 written for non-printable characters) are kept as they are.
+	buf := make([]byte, 0, len(tok))
+	for i := 0; i < len(tok); i++ {
+		if tok[i] == '\\' && i+1 < len(tok) && (tok[i+1] == '"' || tok[i+1] == '\\') {
+			i++
+		}
+		buf = append(buf, tok[i])
 	}
-	return ok
+	return buf
 }
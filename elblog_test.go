@@ -3,11 +3,15 @@ package elblog
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"reflect"
 	"runtime"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -45,26 +49,26 @@ func TestExample(t *testing.T) {
 				IP:   net.ParseIP("10.0.0.1"),
 				Port: 80,
 			},
-			RequestProcessingTime: func() time.Duration {
-				d, _ := time.ParseDuration("73µs")
-				return d
-			}(),
-			BackendProcessingTime: func() time.Duration {
-				d, _ := time.ParseDuration("1.048ms")
-				return d
-			}(),
-			ResponseProcessingTime: func() time.Duration {
-				d, _ := time.ParseDuration("57µs")
-				return d
-			}(),
-			ELBStatusCode:     http.StatusOK,
-			BackendStatusCode: "200",
-			ReceivedBytes:     0,
-			SentBytes:         29,
-			Request:           "GET http://www.example.com:80/ HTTP/1.1",
-			UserAgent:         "curl/7.38.0",
-			SSLCipher:         "-",
-			SSLProtocol:       "-",
+			RequestProcessingTime:  validDuration("73µs"),
+			BackendProcessingTime:  validDuration("1.048ms"),
+			ResponseProcessingTime: validDuration("57µs"),
+			ELBStatusCode:          http.StatusOK,
+			BackendStatusCode:      NullInt{Int: 200, Valid: true},
+			ReceivedBytes:          0,
+			SentBytes:              29,
+			Request:                "GET http://www.example.com:80/ HTTP/1.1",
+			RequestLine: RequestLine{
+				Method:     "GET",
+				RequestURI: "http://www.example.com:80/",
+				URL:        mustParseURL("http://www.example.com:80/"),
+				Proto:      "HTTP/1.1",
+				ProtoMajor: 1,
+				ProtoMinor: 1,
+			},
+			UserAgent:   "curl/7.38.0",
+			SSLCipher:   "-",
+			SSLProtocol: "-",
+			Version:     Version2015,
 		},
 		Log{
 			Type: "https",
@@ -81,23 +85,22 @@ func TestExample(t *testing.T) {
 				IP:   net.ParseIP("10.0.0.1"),
 				Port: 80,
 			},
-			RequestProcessingTime: func() time.Duration {
-				d, _ := time.ParseDuration("0s")
-				return d
-			}(),
-			BackendProcessingTime: func() time.Duration {
-				d, _ := time.ParseDuration("2ms")
-				return d
-			}(),
-			ResponseProcessingTime: func() time.Duration {
-				d, _ := time.ParseDuration("0s")
-				return d
-			}(),
-			ELBStatusCode:       http.StatusOK,
-			BackendStatusCode:   "200",
-			ReceivedBytes:       145,
-			SentBytes:           1396,
-			Request:             "GET https://www.example.com:443/ HTTP/1.1",
+			RequestProcessingTime:  validDuration("0s"),
+			BackendProcessingTime:  validDuration("2ms"),
+			ResponseProcessingTime: validDuration("0s"),
+			ELBStatusCode:          http.StatusOK,
+			BackendStatusCode:      NullInt{Int: 200, Valid: true},
+			ReceivedBytes:          145,
+			SentBytes:              1396,
+			Request:                "GET https://www.example.com:443/ HTTP/1.1",
+			RequestLine: RequestLine{
+				Method:     "GET",
+				RequestURI: "https://www.example.com:443/",
+				URL:        mustParseURL("https://www.example.com:443/"),
+				Proto:      "HTTP/1.1",
+				ProtoMajor: 1,
+				ProtoMinor: 1,
+			},
 			UserAgent:           "-",
 			SSLCipher:           "ECDHE-RSA-AES128-GCM-SHA256",
 			SSLProtocol:         "TLSv1.2",
@@ -106,6 +109,7 @@ func TestExample(t *testing.T) {
 			DomainName:          "www.example.com",
 			ChosenCertARN:       "session-reused",
 			MatchedRulePriority: "0",
+			Version:             Version2017,
 		},
 		// entries from june 2019
 		Log{
@@ -123,23 +127,22 @@ func TestExample(t *testing.T) {
 				IP:   net.ParseIP("10.0.0.1"),
 				Port: 80,
 			},
-			RequestProcessingTime: func() time.Duration {
-				d, _ := time.ParseDuration("73µs")
-				return d
-			}(),
-			BackendProcessingTime: func() time.Duration {
-				d, _ := time.ParseDuration("1.048ms")
-				return d
-			}(),
-			ResponseProcessingTime: func() time.Duration {
-				d, _ := time.ParseDuration("57µs")
-				return d
-			}(),
-			ELBStatusCode:       http.StatusOK,
-			BackendStatusCode:   "200",
-			ReceivedBytes:       0,
-			SentBytes:           29,
-			Request:             "GET http://www.example.com:80/ HTTP/1.1",
+			RequestProcessingTime:  validDuration("73µs"),
+			BackendProcessingTime:  validDuration("1.048ms"),
+			ResponseProcessingTime: validDuration("57µs"),
+			ELBStatusCode:          http.StatusOK,
+			BackendStatusCode:      NullInt{Int: 200, Valid: true},
+			ReceivedBytes:          0,
+			SentBytes:              29,
+			Request:                "GET http://www.example.com:80/ HTTP/1.1",
+			RequestLine: RequestLine{
+				Method:     "GET",
+				RequestURI: "http://www.example.com:80/",
+				URL:        mustParseURL("http://www.example.com:80/"),
+				Proto:      "HTTP/1.1",
+				ProtoMajor: 1,
+				ProtoMinor: 1,
+			},
 			UserAgent:           "curl/7.38.0",
 			SSLCipher:           "-",
 			SSLProtocol:         "-",
@@ -152,6 +155,7 @@ func TestExample(t *testing.T) {
 			ActionsExecuted:     "forward",
 			RedirectURL:         "-",
 			ErrorReason:         "-",
+			Version:             Version2019,
 		},
 		Log{
 			Type: "https",
@@ -168,23 +172,22 @@ func TestExample(t *testing.T) {
 				IP:   net.ParseIP("10.0.0.1"),
 				Port: 80,
 			},
-			RequestProcessingTime: func() time.Duration {
-				d, _ := time.ParseDuration("0s")
-				return d
-			}(),
-			BackendProcessingTime: func() time.Duration {
-				d, _ := time.ParseDuration("2ms")
-				return d
-			}(),
-			ResponseProcessingTime: func() time.Duration {
-				d, _ := time.ParseDuration("0s")
-				return d
-			}(),
-			ELBStatusCode:       http.StatusOK,
-			BackendStatusCode:   "200",
-			ReceivedBytes:       145,
-			SentBytes:           1396,
-			Request:             "GET https://www.example.com:443/ HTTP/1.1",
+			RequestProcessingTime:  validDuration("0s"),
+			BackendProcessingTime:  validDuration("2ms"),
+			ResponseProcessingTime: validDuration("0s"),
+			ELBStatusCode:          http.StatusOK,
+			BackendStatusCode:      NullInt{Int: 200, Valid: true},
+			ReceivedBytes:          145,
+			SentBytes:              1396,
+			Request:                "GET https://www.example.com:443/ HTTP/1.1",
+			RequestLine: RequestLine{
+				Method:     "GET",
+				RequestURI: "https://www.example.com:443/",
+				URL:        mustParseURL("https://www.example.com:443/"),
+				Proto:      "HTTP/1.1",
+				ProtoMajor: 1,
+				ProtoMinor: 1,
+			},
 			UserAgent:           "-",
 			SSLCipher:           "ECDHE-RSA-AES128-GCM-SHA256",
 			SSLProtocol:         "TLSv1.2",
@@ -197,6 +200,7 @@ func TestExample(t *testing.T) {
 			ActionsExecuted:     "authenticate,forward",
 			RedirectURL:         "-",
 			ErrorReason:         "-",
+			Version:             Version2019,
 		},
 		// entries with other fields
 		Log{
@@ -214,23 +218,22 @@ func TestExample(t *testing.T) {
 				IP:   net.ParseIP("10.0.0.1"),
 				Port: 80,
 			},
-			RequestProcessingTime: func() time.Duration {
-				d, _ := time.ParseDuration("0s")
-				return d
-			}(),
-			BackendProcessingTime: func() time.Duration {
-				d, _ := time.ParseDuration("2ms")
-				return d
-			}(),
-			ResponseProcessingTime: func() time.Duration {
-				d, _ := time.ParseDuration("0s")
-				return d
-			}(),
-			ELBStatusCode:       http.StatusOK,
-			BackendStatusCode:   "200",
-			ReceivedBytes:       145,
-			SentBytes:           1396,
-			Request:             "GET https://www.example.com:443/ HTTP/1.1",
+			RequestProcessingTime:  validDuration("0s"),
+			BackendProcessingTime:  validDuration("2ms"),
+			ResponseProcessingTime: validDuration("0s"),
+			ELBStatusCode:          http.StatusOK,
+			BackendStatusCode:      NullInt{Int: 200, Valid: true},
+			ReceivedBytes:          145,
+			SentBytes:              1396,
+			Request:                "GET https://www.example.com:443/ HTTP/1.1",
+			RequestLine: RequestLine{
+				Method:     "GET",
+				RequestURI: "https://www.example.com:443/",
+				URL:        mustParseURL("https://www.example.com:443/"),
+				Proto:      "HTTP/1.1",
+				ProtoMajor: 1,
+				ProtoMinor: 1,
+			},
 			UserAgent:           "-",
 			SSLCipher:           "ECDHE-RSA-AES128-GCM-SHA256",
 			SSLProtocol:         "TLSv1.2",
@@ -244,6 +247,7 @@ func TestExample(t *testing.T) {
 			RedirectURL:         "-",
 			ErrorReason:         "-",
 			OtherFields:         "",
+			Version:             Version2019,
 		},
 		// entry from august 2020
 		Log{
@@ -261,23 +265,22 @@ func TestExample(t *testing.T) {
 				IP:   net.ParseIP("10.0.0.1"),
 				Port: 80,
 			},
-			RequestProcessingTime: func() time.Duration {
-				d, _ := time.ParseDuration("86ms")
-				return d
-			}(),
-			BackendProcessingTime: func() time.Duration {
-				d, _ := time.ParseDuration("48ms")
-				return d
-			}(),
-			ResponseProcessingTime: func() time.Duration {
-				d, _ := time.ParseDuration("37ms")
-				return d
-			}(),
-			ELBStatusCode:        http.StatusOK,
-			BackendStatusCode:    "200",
-			ReceivedBytes:        0,
-			SentBytes:            57,
-			Request:              "GET https://www.example.com:443/ HTTP/1.1",
+			RequestProcessingTime:  validDuration("86ms"),
+			BackendProcessingTime:  validDuration("48ms"),
+			ResponseProcessingTime: validDuration("37ms"),
+			ELBStatusCode:          http.StatusOK,
+			BackendStatusCode:      NullInt{Int: 200, Valid: true},
+			ReceivedBytes:          0,
+			SentBytes:              57,
+			Request:                "GET https://www.example.com:443/ HTTP/1.1",
+			RequestLine: RequestLine{
+				Method:     "GET",
+				RequestURI: "https://www.example.com:443/",
+				URL:        mustParseURL("https://www.example.com:443/"),
+				Proto:      "HTTP/1.1",
+				ProtoMajor: 1,
+				ProtoMinor: 1,
+			},
 			UserAgent:            "curl/7.46.0",
 			SSLCipher:            "ECDHE-RSA-AES128-GCM-SHA256",
 			SSLProtocol:          "TLSv1.2",
@@ -295,6 +298,7 @@ func TestExample(t *testing.T) {
 			Classification:       "-",
 			ClassificationReason: "-",
 			OtherFields:          "",
+			Version:              Version2020,
 		},
 		Log{
 			Type: "https",
@@ -311,23 +315,22 @@ func TestExample(t *testing.T) {
 				IP:   net.ParseIP("10.0.0.1"),
 				Port: 80,
 			},
-			RequestProcessingTime: func() time.Duration {
-				d, _ := time.ParseDuration("86ms")
-				return d
-			}(),
-			BackendProcessingTime: func() time.Duration {
-				d, _ := time.ParseDuration("48ms")
-				return d
-			}(),
-			ResponseProcessingTime: func() time.Duration {
-				d, _ := time.ParseDuration("37ms")
-				return d
-			}(),
-			ELBStatusCode:        http.StatusOK,
-			BackendStatusCode:    "200",
-			ReceivedBytes:        0,
-			SentBytes:            57,
-			Request:              "GET https://www.example.com:443/ HTTP/1.1",
+			RequestProcessingTime:  validDuration("86ms"),
+			BackendProcessingTime:  validDuration("48ms"),
+			ResponseProcessingTime: validDuration("37ms"),
+			ELBStatusCode:          http.StatusOK,
+			BackendStatusCode:      NullInt{Int: 200, Valid: true},
+			ReceivedBytes:          0,
+			SentBytes:              57,
+			Request:                "GET https://www.example.com:443/ HTTP/1.1",
+			RequestLine: RequestLine{
+				Method:     "GET",
+				RequestURI: "https://www.example.com:443/",
+				URL:        mustParseURL("https://www.example.com:443/"),
+				Proto:      "HTTP/1.1",
+				ProtoMajor: 1,
+				ProtoMinor: 1,
+			},
 			UserAgent:            "curl/7.46.0",
 			SSLCipher:            "ECDHE-RSA-AES128-GCM-SHA256",
 			SSLProtocol:          "TLSv1.2",
@@ -345,6 +348,13 @@ func TestExample(t *testing.T) {
 			Classification:       "-",
 			ClassificationReason: "-",
 			OtherFields:          "future-entry-1 \"future-entry-2\" 3 future/entry/4",
+			TrailingFields: map[string]string{
+				"conn_trace_id":            "future-entry-1",
+				"transformed_host":         "future-entry-2",
+				"transformed_uri":          "3",
+				"request_transform_status": "future/entry/4",
+			},
+			Version: Version2020,
 		},
 	}
 
@@ -375,23 +385,22 @@ func TestParse(t *testing.T) {
 					IP:   net.ParseIP("10.0.0.1"),
 					Port: 80,
 				},
-				RequestProcessingTime: func() time.Duration {
-					d, _ := time.ParseDuration("73µs")
-					return d
-				}(),
-				BackendProcessingTime: func() time.Duration {
-					d, _ := time.ParseDuration("1.048ms")
-					return d
-				}(),
-				ResponseProcessingTime: func() time.Duration {
-					d, _ := time.ParseDuration("57µs")
-					return d
-				}(),
-				ELBStatusCode:       http.StatusOK,
-				BackendStatusCode:   "200",
-				ReceivedBytes:       0,
-				SentBytes:           29,
-				Request:             "GET http://www.example.com:80/ HTTP/1.1",
+				RequestProcessingTime:  validDuration("73µs"),
+				BackendProcessingTime:  validDuration("1.048ms"),
+				ResponseProcessingTime: validDuration("57µs"),
+				ELBStatusCode:          http.StatusOK,
+				BackendStatusCode:      NullInt{Int: 200, Valid: true},
+				ReceivedBytes:          0,
+				SentBytes:              29,
+				Request:                "GET http://www.example.com:80/ HTTP/1.1",
+				RequestLine: RequestLine{
+					Method:     "GET",
+					RequestURI: "http://www.example.com:80/",
+					URL:        mustParseURL("http://www.example.com:80/"),
+					Proto:      "HTTP/1.1",
+					ProtoMajor: 1,
+					ProtoMinor: 1,
+				},
 				UserAgent:           "curl/7.38.0",
 				SSLCipher:           "-",
 				SSLProtocol:         "-",
@@ -404,6 +413,7 @@ func TestParse(t *testing.T) {
 				ActionsExecuted:     "forward",
 				RedirectURL:         "-",
 				ErrorReason:         "-",
+				Version:             Version2019,
 			},
 		},
 	}
@@ -422,6 +432,686 @@ func TestParse(t *testing.T) {
 	}
 }
 
+func TestParse_addresses(t *testing.T) {
+	cases := map[string]struct {
+		client, target string
+		from, to       *net.TCPAddr
+	}{
+		"ipv4": {
+			client: "192.168.131.39:2817",
+			target: "10.0.0.1:80",
+			from:   &net.TCPAddr{IP: net.ParseIP("192.168.131.39"), Port: 2817},
+			to:     &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 80},
+		},
+		"ipv6-bracketed": {
+			client: "[2001:db8::ff00:42:8329]:2817",
+			target: "[fd00:ec2::254]:8080",
+			from:   &net.TCPAddr{IP: net.ParseIP("2001:db8::ff00:42:8329"), Port: 2817},
+			to:     &net.TCPAddr{IP: net.ParseIP("fd00:ec2::254"), Port: 8080},
+		},
+		"ipv6-unbracketed": {
+			client: "2001:db8:85a3::8a2e:370:7334:2817",
+			target: "fd00:ec2::254:8080",
+			from:   &net.TCPAddr{IP: net.ParseIP("2001:db8:85a3::8a2e:370:7334"), Port: 2817},
+			to:     &net.TCPAddr{IP: net.ParseIP("fd00:ec2::254"), Port: 8080},
+		},
+		"ipv6-no-port": {
+			client: "2001:db8::",
+			target: "[::1]",
+			from:   &net.TCPAddr{IP: net.ParseIP("2001:db8::")},
+			to:     &net.TCPAddr{IP: net.ParseIP("::1")},
+		},
+		"ipv4-mapped-ipv6": {
+			client: "[::ffff:192.168.131.39]:2817",
+			target: "::ffff:10.0.0.1:80",
+			from:   &net.TCPAddr{IP: net.ParseIP("192.168.131.39"), Port: 2817},
+			to:     &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 80},
+		},
+		"no-target": {
+			client: "[2001:db8::1]:2817",
+			target: "-",
+			from:   &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 2817},
+			to:     nil,
+		},
+	}
+
+	for hint, c := range cases {
+		t.Run(hint, func(t *testing.T) {
+			line := `https 2018-07-02T22:23:00.186641Z app/my-loadbalancer/50dc6c495c0c9188 ` + c.client + ` ` + c.target + ` 0.000 0.001 0.000 200 200 34 366 "GET https://www.example.com:443/ HTTP/1.1" "curl/7.46.0" - -`
+			got, err := Parse([]byte(line))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+			if !reflect.DeepEqual(got.From, c.from) {
+				t.Errorf("wrong client address, expected %v but got %v", c.from, got.From)
+			}
+			if !reflect.DeepEqual(got.To, c.to) {
+				t.Errorf("wrong target address, expected %v but got %v", c.to, got.To)
+			}
+		})
+	}
+}
+
+func TestParse_invalidAddress(t *testing.T) {
+	cases := map[string]string{
+		"invalid-ip":         "example.com:80",
+		"invalid-port":       "10.0.0.1:http",
+		"port-out-of-range":  "10.0.0.1:65536",
+		"unclosed-bracket":   "[2001:db8::1:80",
+		"garbage-after-host": "[2001:db8::1]80",
+		"ipv6-zone":          "[fe80::1%eth0]:80",
+	}
+
+	for hint, target := range cases {
+		t.Run(hint, func(t *testing.T) {
+			line := `https 2018-07-02T22:23:00.186641Z app/my-loadbalancer/50dc6c495c0c9188 192.168.131.39:2817 ` + target + ` 0.000 0.001 0.000 200 200 34 366 "GET https://www.example.com:443/ HTTP/1.1" "curl/7.46.0" - -`
+			if _, err := Parse([]byte(line)); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestParse_notAvailable(t *testing.T) {
+	line := `http 2018-07-02T22:23:00.186641Z app/my-loadbalancer/50dc6c495c0c9188 192.168.131.39:2817 10.0.0.1:80 0.000 -1 -1 502 - 34 366 "GET http://www.example.com:80/ HTTP/1.1" "curl/7.46.0" - -`
+	got, err := Parse([]byte(line))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if expected := (NullDuration{Valid: true}); got.RequestProcessingTime != expected {
+		t.Errorf("wrong request processing time, expected %v but got %v", expected, got.RequestProcessingTime)
+	}
+	if got.BackendProcessingTime.Valid {
+		t.Errorf("backend processing time should not be available, got %v", got.BackendProcessingTime)
+	}
+	if got.ResponseProcessingTime.Valid {
+		t.Errorf("response processing time should not be available, got %v", got.ResponseProcessingTime)
+	}
+	if got.ELBStatusCode != http.StatusBadGateway {
+		t.Errorf("wrong elb status code, expected %d but got %d", http.StatusBadGateway, got.ELBStatusCode)
+	}
+	if got.BackendStatusCode.Valid {
+		t.Errorf("backend status code should not be available, got %v", got.BackendStatusCode)
+	}
+}
+
+func TestParse_parseError(t *testing.T) {
+	cases := map[string]struct {
+		given    string
+		classic  bool
+		expected ParseError
+		cause    error
+	}{
+		"time": {
+			given:    `http yesterday my-loadbalancer 192.168.131.39:2817 10.0.0.1:80 0.000073 0.001048 0.000057 200 200 0 29`,
+			expected: ParseError{Index: 1, Field: "time", Token: "yesterday", Offset: 5},
+		},
+		"status-code": {
+			given:    `http 2015-05-13T23:39:43.945958Z my-loadbalancer 192.168.131.39:2817 10.0.0.1:80 0.000073 0.001048 0.000057 OK 200 0 29`,
+			expected: ParseError{Index: 8, Field: "elb_status_code", Token: "OK", Offset: 108},
+			cause:    strconv.ErrSyntax,
+		},
+		"multiple-spaces": {
+			given:    `http 2015-05-13T23:39:43.945958Z my-loadbalancer 192.168.131.39:2817 10.0.0.1:80 0.000073 0.001048 0.000057 200   fine 0 29`,
+			expected: ParseError{Index: 9, Field: "target_status_code", Token: "fine", Offset: 114},
+			cause:    strconv.ErrSyntax,
+		},
+		"classic": {
+			given:    `2015-05-13T23:39:43.945958Z my-loadbalancer 192.168.131.39:2817 10.0.0.1:80 0.000073 0.001048 0.000057 200 200 0 many`,
+			classic:  true,
+			expected: ParseError{Index: 10, Field: "sent_bytes", Token: "many", Offset: 113},
+			cause:    strconv.ErrSyntax,
+		},
+	}
+
+	for hint, c := range cases {
+		t.Run(hint, func(t *testing.T) {
+			var err error
+			if c.classic {
+				_, err = ParseClassic([]byte(c.given))
+			} else {
+				_, err = Parse([]byte(c.given))
+			}
+			var got *ParseError
+			if !errors.As(err, &got) {
+				t.Fatalf("expected ParseError but got: %v", err)
+			}
+			if got.Index != c.expected.Index || got.Field != c.expected.Field || got.Token != c.expected.Token || got.Offset != c.expected.Offset {
+				t.Errorf("expected:\n	%+v but got:\n	%+v", c.expected, *got)
+			}
+			if c.given[got.Offset:got.Offset+len(got.Token)] != got.Token {
+				t.Errorf("offset %d does not point at token %q", got.Offset, got.Token)
+			}
+			if c.cause != nil && !errors.Is(err, c.cause) {
+				t.Errorf("expected error to wrap %v, got: %v", c.cause, err)
+			}
+		})
+	}
+}
+
+func TestParseInto(t *testing.T) {
+	lines := []string{
+		`https 2018-07-02T22:23:00.186641Z app/my-loadbalancer/50dc6c495c0c9188 192.168.131.39:2817 10.0.0.1:80 0.086 0.048 0.037 200 200 0 57 "GET https://www.example.com:443/ HTTP/1.1" "curl/7.46.0" ECDHE-RSA-AES128-GCM-SHA256 TLSv1.2 arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067 "Root=1-58337281-1d84f3d73c47ec4e58577259" "www.example.com" "arn:aws:acm:us-east-2:123456789012:certificate/12345678-1234-1234-1234-123456789012" 1 2018-07-02T22:22:48.364000Z "authenticate,forward" "-" "-" "10.0.0.1:80" "200" "-" "-" future`,
+		`http 2015-05-13T23:39:43.945958Z my-loadbalancer [2001:db8::1]:2817 - -1 -1 -1 503 - 0 29 "GET http://www.example.com:80/ HTTP/1.1" "curl \"7.38.0\"" - -`,
+		`http 2015-05-13T23:39:43.945958Z my-loadbalancer 192.168.131.39:2817 10.0.0.1:80 0.000073 0.001048 0.000057 200 200 0 29 "GET http://www.example.com:80/ HTTP/1.1" "curl/7.38.0" - -`,
+	}
+
+	for hint, opts := range map[string]ParseOptions{
+		"copy":    {},
+		"no-copy": {NoCopy: true},
+	} {
+		t.Run(hint, func(t *testing.T) {
+			var got Log
+			for i, line := range lines {
+				from := got.From
+				if err := opts.ParseInto([]byte(line), &got); err != nil {
+					t.Fatalf("unexpected error: %s", err.Error())
+				}
+				expected, err := Parse([]byte(line))
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err.Error())
+				}
+				if !reflect.DeepEqual(got, *expected) {
+					t.Errorf("line %d, expected:\n	%v but got:\n	%v", i, *expected, got)
+				}
+				if from != nil && got.From != from {
+					t.Errorf("line %d, address should be reused", i)
+				}
+			}
+		})
+	}
+}
+
+func TestParseInto_noCopy(t *testing.T) {
+	line := []byte(`http 2015-05-13T23:39:43.945958Z my-loadbalancer 192.168.131.39:2817 10.0.0.1:80 0.000073 0.001048 0.000057 200 200 0 29 "GET http://www.example.com:80/ HTTP/1.1" "curl/7.38.0" - -`)
+	var log Log
+	if err := (ParseOptions{NoCopy: true}).ParseInto(line, &log); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	copy(line, "wsss")
+	if log.Type != "wsss" {
+		t.Errorf("fields should refer to the line, got: %s", log.Type)
+	}
+	if err := ParseInto(line, &log); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	copy(line, "http")
+	if log.Type != "wsss" {
+		t.Errorf("fields should be copied, got: %s", log.Type)
+	}
+}
+
+func TestParse_strict(t *testing.T) {
+	data, err := os.ReadFile("data.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	strict := ParseOptions{Mode: ModeStrict}
+	for i, line := range bytes.Split(bytes.TrimSpace(data), []byte("\n")) {
+		if _, err := strict.Parse(line); err != nil {
+			t.Errorf("line %d, unexpected error: %s", i, err.Error())
+		}
+	}
+
+	full := `https 2018-07-02T22:23:00.186641Z app/my-loadbalancer/50dc6c495c0c9188 192.168.131.39:2817 10.0.0.1:80 0.086 0.048 0.037 200 200 0 57 "GET https://www.example.com:443/ HTTP/1.1" "curl/7.46.0" ECDHE-RSA-AES128-GCM-SHA256 TLSv1.2 arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067 "Root=1-58337281-1d84f3d73c47ec4e58577259" "www.example.com" "arn:aws:acm:us-east-2:123456789012:certificate/12345678-1234-1234-1234-123456789012" 1 2018-07-02T22:22:48.364000Z "authenticate,forward" "-" "-" "10.0.0.1:80" "200" "-" "-"`
+	cases := map[string]struct {
+		given string
+		field string
+		err   error
+	}{
+		"truncated-after-user-agent": {
+			given: full[:strings.Index(full, " ECDHE")],
+			field: "ssl_cipher",
+			err:   ErrFieldCount,
+		},
+		"truncated-after-request-creation-time": {
+			given: full[:strings.Index(full, ` "authenticate`)],
+			field: "actions_executed",
+			err:   ErrFieldCount,
+		},
+		"empty": {
+			given: "",
+			field: "type",
+			err:   ErrFieldCount,
+		},
+		"unterminated-quote": {
+			given: full[:strings.Index(full, `www.example.com"`)],
+			field: "domain_name",
+			err:   ErrUnterminatedQuote,
+		},
+		"unterminated-escaped-quote": {
+			given: full[:strings.Index(full, `"curl`)] + `"curl \"7.46.0\"`,
+			field: "user_agent",
+			err:   ErrUnterminatedQuote,
+		},
+	}
+
+	for hint, c := range cases {
+		t.Run(hint, func(t *testing.T) {
+			if _, err := Parse([]byte(c.given)); err != nil {
+				t.Fatalf("lenient mode should accept the line, got: %v", err)
+			}
+			_, err := strict.Parse([]byte(c.given))
+			var perr *ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("expected ParseError but got: %v", err)
+			}
+			if perr.Field != c.field || !errors.Is(err, c.err) {
+				t.Errorf("expected %v at %s, got: %v", c.err, c.field, err)
+			}
+		})
+	}
+}
+
+func TestParseClassic_strict(t *testing.T) {
+	line := `2015-05-13T23:39:43.945958Z my-loadbalancer 192.168.131.39:2817 10.0.0.1:80 0.000073 0.001048 0.000057 200 200 0 29 "GET http://www.example.com:80/ HTTP/1.1" "curl/7.38.0" - -`
+	strict := ParseOptions{Mode: ModeStrict}
+	if _, err := strict.ParseClassic([]byte(line)); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	for _, given := range []string{strings.TrimSuffix(line, " -"), line + " -"} {
+		if _, err := strict.ParseClassic([]byte(given)); !errors.Is(err, ErrFieldCount) {
+			t.Errorf("expected ErrFieldCount, got: %v", err)
+		}
+	}
+}
+
+func TestParse_trailingFields(t *testing.T) {
+	prefix := `https 2018-07-02T22:23:00.186641Z app/my-loadbalancer/50dc6c495c0c9188 192.168.131.39:2817 10.0.0.1:80 0.086 0.048 0.037 200 200 0 57 "GET https://www.example.com:443/ HTTP/1.1" "curl/7.46.0" ECDHE-RSA-AES128-GCM-SHA256 TLSv1.2 arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067 "Root=1-58337281-1d84f3d73c47ec4e58577259" "www.example.com" "arn:aws:acm:us-east-2:123456789012:certificate/12345678-1234-1234-1234-123456789012" 1 2018-07-02T22:22:48.364000Z "authenticate,forward" "-" "-" "10.0.0.1:80" "200" "-" "-" `
+
+	cases := map[string]struct {
+		given    string
+		names    []string
+		expected map[string]string
+		unknown  string
+	}{
+		"none": {
+			given: strings.TrimSuffix(prefix, " "),
+		},
+		"conn-trace-id": {
+			given:    prefix + `TID_1234`,
+			expected: map[string]string{"conn_trace_id": "TID_1234"},
+		},
+		"all": {
+			given: prefix + `TID_1234 "www.example.com" "/index.html" "-" "new field" 7`,
+			expected: map[string]string{
+				"conn_trace_id":            "TID_1234",
+				"transformed_host":         "www.example.com",
+				"transformed_uri":          "/index.html",
+				"request_transform_status": "-",
+			},
+			unknown: `"new field" 7`,
+		},
+		"custom-names": {
+			given:    prefix + `TID_1234 "x-amzn-trace-id: Root=1" unknown`,
+			names:    []string{"conn_trace_id", "tracing_headers"},
+			expected: map[string]string{"conn_trace_id": "TID_1234", "tracing_headers": "x-amzn-trace-id: Root=1"},
+			unknown:  "unknown",
+		},
+		"no-names": {
+			given:   prefix + `TID_1234 "www.example.com"`,
+			names:   []string{},
+			unknown: `TID_1234 "www.example.com"`,
+		},
+	}
+
+	for hint, c := range cases {
+		t.Run(hint, func(t *testing.T) {
+			got, err := ParseOptions{TrailingFields: c.names}.Parse([]byte(c.given))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+			if !reflect.DeepEqual(got.TrailingFields, c.expected) {
+				t.Errorf("expected:\n	%v but got:\n	%v", c.expected, got.TrailingFields)
+			}
+			if got.UnknownFields != c.unknown {
+				t.Errorf("wrong unknown fields, expected %q but got %q", c.unknown, got.UnknownFields)
+			}
+			if got.OtherFields != strings.TrimPrefix(c.given, prefix) && c.given != strings.TrimSuffix(prefix, " ") {
+				t.Errorf("other fields should be kept intact, got %q", got.OtherFields)
+			}
+		})
+	}
+}
+
+func TestParseClassic(t *testing.T) {
+	cases := map[string]struct {
+		given    string
+		expected Log
+	}{
+		"http": {
+			given: `2015-05-13T23:39:43.945958Z my-loadbalancer 192.168.131.39:2817 10.0.0.1:80 0.000073 0.001048 0.000057 200 200 0 29 "GET http://www.example.com:80/ HTTP/1.1" "curl/7.38.0" - -`,
+			expected: Log{
+				Time: func() time.Time {
+					t, _ := time.Parse(time.RFC3339, "2015-05-13T23:39:43.945958Z")
+					return t
+				}(),
+				Name: "my-loadbalancer",
+				From: &net.TCPAddr{
+					IP:   net.ParseIP("192.168.131.39"),
+					Port: 2817,
+				},
+				To: &net.TCPAddr{
+					IP:   net.ParseIP("10.0.0.1"),
+					Port: 80,
+				},
+				RequestProcessingTime:  NullDuration{Duration: 73 * time.Microsecond, Valid: true},
+				BackendProcessingTime:  NullDuration{Duration: 1048 * time.Microsecond, Valid: true},
+				ResponseProcessingTime: NullDuration{Duration: 57 * time.Microsecond, Valid: true},
+				ELBStatusCode:          http.StatusOK,
+				BackendStatusCode:      NullInt{Int: 200, Valid: true},
+				ReceivedBytes:          0,
+				SentBytes:              29,
+				Request:                "GET http://www.example.com:80/ HTTP/1.1",
+				RequestLine: RequestLine{
+					Method:     "GET",
+					RequestURI: "http://www.example.com:80/",
+					URL:        mustParseURL("http://www.example.com:80/"),
+					Proto:      "HTTP/1.1",
+					ProtoMajor: 1,
+					ProtoMinor: 1,
+				},
+				UserAgent:   "curl/7.38.0",
+				SSLCipher:   "-",
+				SSLProtocol: "-",
+				Version:     VersionClassic,
+			},
+		},
+		"ssl-listener": {
+			given: `2015-05-13T23:39:43.945958Z my-loadbalancer 192.168.131.39:2817 10.0.0.2:80 0.001065 0.000015 0.000023 - - 57 502 "- - - " "-" ECDHE-ECDSA-AES128-GCM-SHA256 TLSv1.2`,
+			expected: Log{
+				Time: func() time.Time {
+					t, _ := time.Parse(time.RFC3339, "2015-05-13T23:39:43.945958Z")
+					return t
+				}(),
+				Name: "my-loadbalancer",
+				From: &net.TCPAddr{
+					IP:   net.ParseIP("192.168.131.39"),
+					Port: 2817,
+				},
+				To: &net.TCPAddr{
+					IP:   net.ParseIP("10.0.0.2"),
+					Port: 80,
+				},
+				RequestProcessingTime:  NullDuration{Duration: 1065 * time.Microsecond, Valid: true},
+				BackendProcessingTime:  NullDuration{Duration: 15 * time.Microsecond, Valid: true},
+				ResponseProcessingTime: NullDuration{Duration: 23 * time.Microsecond, Valid: true},
+				BackendStatusCode:      NullInt{},
+				ReceivedBytes:          57,
+				SentBytes:              502,
+				Request:                "- - - ",
+				UserAgent:              "-",
+				SSLCipher:              "ECDHE-ECDSA-AES128-GCM-SHA256",
+				SSLProtocol:            "TLSv1.2",
+				Version:                VersionClassic,
+			},
+		},
+		"tcp-listener": {
+			given: `2015-05-13T23:39:43.945958Z my-loadbalancer 192.168.131.39:2817 10.0.0.2:80 0.001069 0.000028 0.000041 - - 82 305 "- - - " "-" - -`,
+			expected: Log{
+				Time: func() time.Time {
+					t, _ := time.Parse(time.RFC3339, "2015-05-13T23:39:43.945958Z")
+					return t
+				}(),
+				Name: "my-loadbalancer",
+				From: &net.TCPAddr{
+					IP:   net.ParseIP("192.168.131.39"),
+					Port: 2817,
+				},
+				To: &net.TCPAddr{
+					IP:   net.ParseIP("10.0.0.2"),
+					Port: 80,
+				},
+				RequestProcessingTime:  NullDuration{Duration: 1069 * time.Microsecond, Valid: true},
+				BackendProcessingTime:  NullDuration{Duration: 28 * time.Microsecond, Valid: true},
+				ResponseProcessingTime: NullDuration{Duration: 41 * time.Microsecond, Valid: true},
+				BackendStatusCode:      NullInt{},
+				ReceivedBytes:          82,
+				SentBytes:              305,
+				Request:                "- - - ",
+				UserAgent:              "-",
+				SSLCipher:              "-",
+				SSLProtocol:            "-",
+				Version:                VersionClassic,
+			},
+		},
+	}
+
+	for hint, c := range cases {
+		t.Run(hint, func(t *testing.T) {
+			got, err := ParseClassic([]byte(c.given))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			if !reflect.DeepEqual(*got, c.expected) {
+				t.Errorf("expected:\n	%v but got:\n	%v", c.expected, *got)
+			}
+		})
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	cases := map[string]struct {
+		given    string
+		expected Format
+	}{
+		"alb":     {given: `http 2015-05-13T23:39:43.945958Z my-loadbalancer`, expected: FormatALB},
+		"h2":      {given: `h2 2015-05-13T23:39:43.945958Z my-loadbalancer`, expected: FormatALB},
+		"classic": {given: `2015-05-13T23:39:43.945958Z my-loadbalancer`, expected: FormatClassic},
+		"empty":   {given: ``, expected: FormatALB},
+	}
+
+	for hint, c := range cases {
+		t.Run(hint, func(t *testing.T) {
+			if got := DetectFormat([]byte(c.given)); got != c.expected {
+				t.Errorf("expected %d but got %d", c.expected, got)
+			}
+		})
+	}
+}
+
+func TestDecoder_Decode_autoFormat(t *testing.T) {
+	input := `2015-05-13T23:39:43.945958Z my-loadbalancer 192.168.131.39:2817 10.0.0.1:80 0.000073 0.001048 0.000057 200 200 0 29 "GET http://www.example.com:80/ HTTP/1.1" "curl/7.38.0" - -
+http 2015-05-13T23:39:43.945958Z my-loadbalancer 192.168.131.39:2817 10.0.0.1:80 0.000073 0.001048 0.000057 200 200 0 29 "GET http://www.example.com:80/ HTTP/1.1" "curl/7.38.0" - -
+2015-05-13T23:39:43.945958Z my-loadbalancer 192.168.131.39:2817 10.0.0.2:80 0.001069 0.000028 0.000041 - - 82 305 "- - - " "-" - -
+`
+	dec := NewDecoder(bytes.NewBufferString(input), WithFormat(FormatAuto))
+	var types []string
+	for dec.More() {
+		log, err := dec.Decode()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		if log.Name != "my-loadbalancer" {
+			t.Errorf("wrong name: %s", log.Name)
+		}
+		types = append(types, log.Type)
+	}
+	if expected := []string{"", "http", ""}; !reflect.DeepEqual(types, expected) {
+		t.Errorf("expected types %q but got %q", expected, types)
+	}
+}
+
+func TestParseRequestLine(t *testing.T) {
+	cases := map[string]struct {
+		given    string
+		expected RequestLine
+	}{
+		"http": {
+			given: "GET http://www.example.com:80/?q=1 HTTP/1.1",
+			expected: RequestLine{
+				Method:     "GET",
+				RequestURI: "http://www.example.com:80/?q=1",
+				URL:        mustParseURL("http://www.example.com:80/?q=1"),
+				Proto:      "HTTP/1.1",
+				ProtoMajor: 1,
+				ProtoMinor: 1,
+			},
+		},
+		"h2": {
+			given: "POST https://www.example.com:443/api HTTP/2.0",
+			expected: RequestLine{
+				Method:     "POST",
+				RequestURI: "https://www.example.com:443/api",
+				URL:        mustParseURL("https://www.example.com:443/api"),
+				Proto:      "HTTP/2.0",
+				ProtoMajor: 2,
+				ProtoMinor: 0,
+			},
+		},
+		"tcp": {
+			given:    "- - - ",
+			expected: RequestLine{},
+		},
+		"empty": {
+			given:    "",
+			expected: RequestLine{},
+		},
+		"too-short": {
+			given:    "GET /",
+			expected: RequestLine{},
+		},
+		"invalid-url": {
+			given: "GET http://[::1/ HTTP/1.1",
+			expected: RequestLine{
+				Method:     "GET",
+				RequestURI: "http://[::1/",
+				Proto:      "HTTP/1.1",
+				ProtoMajor: 1,
+				ProtoMinor: 1,
+			},
+		},
+		"space-in-url": {
+			given: "GET http://www.example.com:80/a b HTTP/1.1",
+			expected: RequestLine{
+				Method:     "GET",
+				RequestURI: "http://www.example.com:80/a b",
+				URL:        mustParseURL("http://www.example.com:80/a b"),
+				Proto:      "HTTP/1.1",
+				ProtoMajor: 1,
+				ProtoMinor: 1,
+			},
+		},
+		"invalid-proto": {
+			given: "GET http://www.example.com:80/ garbage",
+			expected: RequestLine{
+				Method:     "GET",
+				RequestURI: "http://www.example.com:80/",
+				URL:        mustParseURL("http://www.example.com:80/"),
+				Proto:      "garbage",
+			},
+		},
+	}
+
+	for hint, c := range cases {
+		t.Run(hint, func(t *testing.T) {
+			got := ParseRequestLine(c.given)
+			if !reflect.DeepEqual(got, c.expected) {
+				t.Errorf("expected:\n	%v but got:\n	%v", c.expected, got)
+			}
+		})
+	}
+}
+
+func TestScan(t *testing.T) {
+	cases := map[string]struct {
+		given    string
+		expected []string
+	}{
+		"words":                   {given: `a bb  ccc`, expected: []string{"a", "bb", "ccc"}},
+		"quoted":                  {given: `a "b c" d`, expected: []string{"a", "b c", "d"}},
+		"empty-quoted":            {given: `a "" d`, expected: []string{"a", "", "d"}},
+		"escaped-quote":           {given: `"say \"hi\"" d`, expected: []string{`say "hi"`, "d"}},
+		"escaped-quote-and-space": {given: `"a\" b" c`, expected: []string{`a" b`, "c"}},
+		"escaped-backslash":       {given: `"a\\" b`, expected: []string{`a\`, "b"}},
+		"escaped-backslash-quote": {given: `"a\\\"" b`, expected: []string{`a\"`, "b"}},
+		"other-escape":            {given: `"a\x00b" c`, expected: []string{`a\x00b`, "c"}},
+		"backslash-unquoted":      {given: `a\ b`, expected: []string{`a\`, "b"}},
+		"quoted-list":             {given: `"h2","http/1.1" x`, expected: []string{`h2","http/1.1`, "x"}},
+		"unterminated":            {given: `a "b c`, expected: []string{"a", `"b c`}},
+		"unterminated-escaped":    {given: `a "b\"`, expected: []string{"a", `"b\"`}},
+	}
+
+	for hint, c := range cases {
+		t.Run(hint, func(t *testing.T) {
+			var got []string
+			data := []byte(c.given)
+			for len(data) > 0 {
+				adv, tok, err := scan(data)
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err.Error())
+				}
+				if tok == nil {
+					break
+				}
+				got = append(got, string(tok))
+				data = data[adv:]
+			}
+			if !reflect.DeepEqual(got, c.expected) {
+				t.Errorf("expected %q but got %q", c.expected, got)
+			}
+		})
+	}
+}
+
+// escape quotes s the way load balancer does for quoted fields.
+func escape(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// FuzzParse_userAgent checks that no user agent can break tokenization of the remaining fields.
+// Regressions found by fuzzing are stored in testdata/fuzz/FuzzParse_userAgent.
+func FuzzParse_userAgent(f *testing.F) {
+	for _, ua := range []string{
+		"curl/7.46.0",
+		`Mozilla/5.0 (compatible; "quoted"; like Gecko)`,
+		`"`,
+		`\`,
+		`\"`,
+		`" "`,
+		`- - - `,
+		`a" ECDHE-RSA-AES128-GCM-SHA256 "b`,
+		"\x00\x1b[31m",
+	} {
+		f.Add(ua)
+	}
+	f.Fuzz(func(t *testing.T, ua string) {
+		if strings.ContainsAny(ua, "\n\r") {
+			t.Skip("load balancer never writes multi-line entries")
+		}
+		line := `https 2018-07-02T22:23:00.186641Z app/my-loadbalancer/50dc6c495c0c9188 192.168.131.39:2817 10.0.0.1:80 0.086 0.048 0.037 200 200 0 57 "GET https://www.example.com:443/ HTTP/1.1" ` + escape(ua) + ` ECDHE-RSA-AES128-GCM-SHA256 TLSv1.2 arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067 "Root=1-58337281-1d84f3d73c47ec4e58577259"`
+		log, err := Parse([]byte(line))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		if log.UserAgent != ua {
+			t.Errorf("wrong user agent, expected %q but got %q", ua, log.UserAgent)
+		}
+		if log.SSLCipher != "ECDHE-RSA-AES128-GCM-SHA256" || log.SSLProtocol != "TLSv1.2" {
+			t.Errorf("fields after user agent are shifted: %q %q", log.SSLCipher, log.SSLProtocol)
+		}
+		if log.TraceID != "Root=1-58337281-1d84f3d73c47ec4e58577259" {
+			t.Errorf("wrong trace id: %q", log.TraceID)
+		}
+	})
+}
+
+// FuzzParse checks that Parse does not panic on arbitrary input.
+func FuzzParse(f *testing.F) {
+	file, err := os.ReadFile("data.log")
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, line := range bytes.Split(file, []byte("\n")) {
+		f.Add(line)
+	}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		_, _ = Parse(b)
+		_, _ = ParseClassic(b)
+	})
+}
+
 func TestDecoder_Decode(t *testing.T) {
 	expected := 100
 	buf := buffor(expected)
@@ -443,13 +1133,37 @@ var benchLog Log
 
 func BenchmarkParse(b *testing.B) {
 	data := []byte(`http 2015-05-13T23:39:43.945958Z my-loadbalancer 192.168.131.39:2817 10.0.0.1:80 0.000073 0.001048 0.000057 200 200 0 29 "GET http://www.example.com:80/ HTTP/1.1" "curl/7.38.0" - - arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067 "Root=1-58337262-36d228ad5d99923122bbe354" "-" "-" 0 2018-07-02T22:22:48.364000Z "forward" "-" "-"`)
-	for n := 0; n < b.N; n++ {
-		log, err := Parse(data)
-		if err != nil {
-			b.Fatalf("unexpected error: %s", err.Error())
+	b.Run("parse", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			log, err := Parse(data)
+			if err != nil {
+				b.Fatalf("unexpected error: %s", err.Error())
+			}
+			benchLog = *log
 		}
-		benchLog = *log
-	}
+	})
+	b.Run("parse-into", func(b *testing.B) {
+		b.ReportAllocs()
+		var log Log
+		for n := 0; n < b.N; n++ {
+			if err := ParseInto(data, &log); err != nil {
+				b.Fatalf("unexpected error: %s", err.Error())
+			}
+		}
+		benchLog = log
+	})
+	b.Run("parse-into-no-copy", func(b *testing.B) {
+		b.ReportAllocs()
+		opts := ParseOptions{NoCopy: true}
+		var log Log
+		for n := 0; n < b.N; n++ {
+			if err := opts.ParseInto(data, &log); err != nil {
+				b.Fatalf("unexpected error: %s", err.Error())
+			}
+		}
+		benchLog = log
+	})
 }
 
 func buffor(max int) *bytes.Buffer {
@@ -540,3 +1254,19 @@ func BenchmarkParse_Parallel(b *testing.B) {
 		}
 	}
 }
+
+func mustParseURL(s string) *url.URL {
+	u, err := url.Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+func validDuration(s string) NullDuration {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		panic(err)
+	}
+	return NullDuration{Duration: d, Valid: true}
+}
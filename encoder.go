@@ -0,0 +1,221 @@
+package elblog
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrLineBreak is returned by the encoder if a field contains a line break, which cannot be written to a log line.
+var ErrLineBreak = errors.New("elblog: line break in field")
+
+const (
+	timeLayout     = "2006-01-02T15:04:05.000000Z"
+	timeLayoutNano = "2006-01-02T15:04:05.000000000Z"
+)
+
+// quotedFields marks ALB fields that load balancer writes between quotation marks.
+var quotedFields = [numTokens]bool{
+	12: true, 13: true, 17: true, 18: true, 19: true,
+	22: true, 23: true, 24: true, 25: true, 26: true, 27: true, 28: true,
+}
+
+// Encoder writes logs to an output stream in ALB access log format.
+type Encoder struct {
+	w   io.Writer
+	buf []byte
+}
+
+// NewEncoder allocates new Encoder object for given output.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{
+		w: w,
+	}
+}
+
+// Encode writes log as a single line, followed by a new line, see Log.AppendText.
+func (e *Encoder) Encode(l *Log) error {
+	b, err := l.AppendText(e.buf[:0])
+	if err != nil {
+		return err
+	}
+	e.buf = append(b, '\n')
+	_, err = e.w.Write(e.buf)
+	return err
+}
+
+// MarshalText encodes log as a single line of ALB access log, see AppendText.
+func (l *Log) MarshalText() ([]byte, error) {
+	return l.AppendText(nil)
+}
+
+// UnmarshalText parses single line of ALB access log into l, see Parse. Unlike ParseInto, it does not reuse
+// addresses and maps of l, which may be shared with its copies.
+func (l *Log) UnmarshalText(b []byte) error {
+	log, err := Parse(b)
+	if err != nil {
+		return err
+	}
+	*l = *log
+	return nil
+}
+
+// AppendText appends log encoded as a single line of ALB access log to b, so that Parse returns the same Log.
+// Fields are written up to the last non-empty one, followed by OtherFields as they are. The line is extended with empty
+// fields to match the layout of Version, or of the oldest version having all the fields. Empty fields are written as "".
+// Logs of VersionClassic are written in Classic Load Balancer format, fields introduced by ALB are omitted.
+// Request is written as it is, RequestLine is ignored. Time is written in UTC, with microsecond precision
+// unless it has a finer one. It returns ErrLineBreak if a field contains a line break.
+func (l *Log) AppendText(b []byte) ([]byte, error) {
+	fields := [...]string{
+		13: l.UserAgent,
+		14: l.SSLCipher,
+		15: l.SSLProtocol,
+		16: l.TargetGroupARN,
+		17: l.TraceID,
+		18: l.DomainName,
+		19: l.ChosenCertARN,
+		20: l.MatchedRulePriority,
+		21: l.RequestCreationTime,
+		22: l.ActionsExecuted,
+		23: l.RedirectURL,
+		24: string(l.ErrorReason),
+		25: l.TargetPortList,
+		26: l.TargetStatusCodeList,
+		27: string(l.Classification),
+		28: l.ClassificationReason,
+		29: l.OtherFields,
+	}
+	n := len(fields)
+	for n > 13 && fields[n-1] == "" {
+		n--
+	}
+	if l.Version == VersionClassic {
+		n = numClassicTokens
+	} else {
+		n = layoutFields(n, l.Version)
+	}
+
+	start := len(b)
+	if l.Version != VersionClassic {
+		b = appendField(b, l.Type, false)
+		b = append(b, ' ')
+	}
+	b = appendTime(b, l.Time)
+	b = append(b, ' ')
+	b = appendField(b, l.Name, false)
+	b = append(b, ' ')
+	b = appendAddr(b, l.From)
+	b = append(b, ' ')
+	b = appendAddr(b, l.To)
+	for _, d := range [...]NullDuration{l.RequestProcessingTime, l.BackendProcessingTime, l.ResponseProcessingTime} {
+		b = append(b, ' ')
+		b = appendProcessingTime(b, d)
+	}
+	b = append(b, ' ')
+	if l.ELBStatusCode == 0 {
+		b = append(b, '-')
+	} else {
+		b = strconv.AppendInt(b, int64(l.ELBStatusCode), 10)
+	}
+	b = append(b, ' ')
+	if l.BackendStatusCode.Valid {
+		b = strconv.AppendInt(b, int64(l.BackendStatusCode.Int), 10)
+	} else {
+		b = append(b, '-')
+	}
+	b = append(b, ' ')
+	b = strconv.AppendInt(b, l.ReceivedBytes, 10)
+	b = append(b, ' ')
+	b = strconv.AppendInt(b, l.SentBytes, 10)
+	b = append(b, ' ')
+	b = appendField(b, l.Request, true)
+	for i := 13; i < n && i < 29; i++ {
+		b = append(b, ' ')
+		b = appendField(b, fields[i], quotedFields[i])
+	}
+	if n == len(fields) {
+		b = append(b, ' ')
+		b = append(b, l.OtherFields...)
+	}
+
+	if bytes.ContainsAny(b[start:], "\r\n") {
+		return b[:start], ErrLineBreak
+	}
+	return b, nil
+}
+
+// appendField appends a string field. Fields that are not quoted by load balancer are quoted only if they are empty
+// or contain characters that would break scanning. Backslashes are escaped only where scan would treat them as escapes.
+func appendField(b []byte, s string, quoted bool) []byte {
+	if !quoted && s != "" && !strings.ContainsAny(s, ` "`) {
+		return append(b, s...)
+	}
+	b = append(b, '"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"':
+			b = append(b, '\\', '"')
+		case c == '\\' && (i+1 == len(s) || s[i+1] == '"' || s[i+1] == '\\'):
+			b = append(b, '\\', '\\')
+		default:
+			b = append(b, c)
+		}
+	}
+	return append(b, '"')
+}
+
+func appendTime(b []byte, t time.Time) []byte {
+	t = t.UTC()
+	if t.Nanosecond()%1000 != 0 {
+		return t.AppendFormat(b, timeLayoutNano)
+	}
+	return t.AppendFormat(b, timeLayout)
+}
+
+// appendAddr appends ip:port, IPv6 addresses are bracketed. Missing address is written as "-".
+func appendAddr(b []byte, addr *net.TCPAddr) []byte {
+	if addr == nil {
+		return append(b, '-')
+	}
+	ip := addr.IP.String()
+	if addr.IP.To4() == nil {
+		b = append(b, '[')
+		b = append(b, ip...)
+		b = append(b, ']')
+	} else {
+		b = append(b, ip...)
+	}
+	b = append(b, ':')
+	return strconv.AppendInt(b, int64(addr.Port), 10)
+}
+
+// appendProcessingTime appends processing time in seconds, with at least millisecond precision. Not available time is written as -1.
+func appendProcessingTime(b []byte, d NullDuration) []byte {
+	if !d.Valid {
+		return append(b, "-1"...)
+	}
+	return appendSeconds(b, d.Duration, 3)
+}
+
+// appendSeconds appends duration as decimal number of seconds with at least given number of fractional digits.
+func appendSeconds(b []byte, d time.Duration, digits int) []byte {
+	u := uint64(d)
+	if d < 0 {
+		b = append(b, '-')
+		u = -u
+	}
+	b = strconv.AppendUint(b, u/uint64(time.Second), 10)
+	// leading 1 keeps the zeros, it is cut off
+	var buf [10]byte
+	frac := strconv.AppendUint(buf[:0], u%uint64(time.Second)+uint64(time.Second), 10)[1:]
+	for len(frac) > digits && frac[len(frac)-1] == '0' {
+		frac = frac[:len(frac)-1]
+	}
+	b = append(b, '.')
+	return append(b, frac...)
+}
@@ -0,0 +1,282 @@
+package elblog
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+func TestLog_MarshalText_dataLog(t *testing.T) {
+	data, err := os.ReadFile("data.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, line := range bytes.Split(bytes.TrimSpace(data), []byte("\n")) {
+		expected, err := Parse(line)
+		if err != nil {
+			t.Fatalf("line %d, unexpected error: %s", i, err.Error())
+		}
+		text, err := expected.MarshalText()
+		if err != nil {
+			t.Fatalf("line %d, unexpected error: %s", i, err.Error())
+		}
+		var got Log
+		if err := got.UnmarshalText(text); err != nil {
+			t.Fatalf("line %d, unexpected error: %s", i, err.Error())
+		}
+		if !reflect.DeepEqual(got, *expected) {
+			t.Errorf("line %d, expected:\n	%v but got:\n	%v", i, *expected, got)
+		}
+		again, err := got.MarshalText()
+		if err != nil {
+			t.Fatalf("line %d, unexpected error: %s", i, err.Error())
+		}
+		if !bytes.Equal(again, text) {
+			t.Errorf("line %d, encoding is not stable:\n	%s\n	%s", i, text, again)
+		}
+	}
+}
+
+func TestLog_UnmarshalText_copy(t *testing.T) {
+	data, err := os.ReadFile("data.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+	last := lines[len(lines)-1]
+	other := bytes.Replace(bytes.Replace(last, []byte("192.168.131.39:2817"), []byte("10.1.1.1:1"), 1), []byte("future-entry-1"), []byte("other"), 1)
+
+	var l1 Log
+	if err := l1.UnmarshalText(last); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	from, trailing := l1.From.String(), fmt.Sprint(l1.TrailingFields)
+	l2 := l1
+	if err := l2.UnmarshalText(other); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if l1.From.String() != from || fmt.Sprint(l1.TrailingFields) != trailing {
+		t.Errorf("copy should not be modified, got %s and %v", l1.From, l1.TrailingFields)
+	}
+}
+
+func TestLog_MarshalText(t *testing.T) {
+	cases := map[string]struct {
+		given    Log
+		expected string
+	}{
+		"minimal": {
+			given:    Log{},
+			expected: `"" 0001-01-01T00:00:00.000000Z "" - - -1 -1 -1 - - 0 0 "" "" "" ""`,
+		},
+		"durations": {
+			given: Log{
+				Type:                   "http",
+				Time:                   time.Date(2015, 5, 13, 23, 39, 43, 945958000, time.UTC),
+				Name:                   "my-loadbalancer",
+				RequestProcessingTime:  validDuration("73µs"),
+				BackendProcessingTime:  validDuration("2s"),
+				ResponseProcessingTime: validDuration("1ns"),
+				ELBStatusCode:          200,
+				BackendStatusCode:      NullInt{Int: 200, Valid: true},
+			},
+			expected: `http 2015-05-13T23:39:43.945958Z my-loadbalancer - - 0.000073 2.000 0.000000001 200 200 0 0 "" "" "" ""`,
+		},
+		"addresses": {
+			given: Log{
+				Type: "h2",
+				Time: time.Date(2015, 5, 13, 23, 39, 43, 1, time.UTC),
+				Name: "my-loadbalancer",
+				From: &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 2817},
+				To:   &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 80},
+			},
+			expected: `h2 2015-05-13T23:39:43.000000001Z my-loadbalancer [2001:db8::1]:2817 10.0.0.1:80 -1 -1 -1 - - 0 0 "" "" "" ""`,
+		},
+		"escapes": {
+			given: Log{
+				Type:        "http",
+				Name:        "my load balancer",
+				Request:     `GET http://www.example.com/"\ HTTP/1.1`,
+				UserAgent:   `curl \x00 \"`,
+				SSLProtocol: "TLSv1.2",
+			},
+			expected: `http 0001-01-01T00:00:00.000000Z "my load balancer" - - -1 -1 -1 - - 0 0 "GET http://www.example.com/\"\ HTTP/1.1" "curl \x00 \\\"" "" TLSv1.2`,
+		},
+		"version": {
+			given: Log{
+				Type:       "http",
+				UserAgent:  "curl/7.38.0",
+				DomainName: "www.example.com",
+				Version:    Version2019,
+			},
+			expected: `http 0001-01-01T00:00:00.000000Z "" - - -1 -1 -1 - - 0 0 "" "curl/7.38.0" "" "" "" "" "www.example.com" "" "" "" "" ""`,
+		},
+		"classic": {
+			given: Log{
+				Type:        "http",
+				Time:        time.Date(2015, 5, 13, 23, 39, 43, 945958000, time.UTC),
+				Name:        "my-loadbalancer",
+				Request:     "- - - ",
+				UserAgent:   "-",
+				SSLCipher:   "-",
+				SSLProtocol: "-",
+				DomainName:  "www.example.com",
+				Version:     VersionClassic,
+			},
+			expected: `2015-05-13T23:39:43.945958Z my-loadbalancer - - -1 -1 -1 - - 0 0 "- - - " "-" - -`,
+		},
+		"large-bytes": {
+			given: Log{
+				Type:          "http",
+				ReceivedBytes: 1 << 33,
+				SentBytes:     1<<63 - 1,
+			},
+			expected: `http 0001-01-01T00:00:00.000000Z "" - - -1 -1 -1 - - 8589934592 9223372036854775807 "" "" "" ""`,
+		},
+		"other-fields": {
+			given: Log{
+				Type:        "http",
+				OtherFields: `future "field"`,
+			},
+			expected: `http 0001-01-01T00:00:00.000000Z "" - - -1 -1 -1 - - 0 0 "" "" "" "" "" "" "" "" "" "" "" "" "" "" "" "" "" future "field"`,
+		},
+	}
+
+	for hint, c := range cases {
+		t.Run(hint, func(t *testing.T) {
+			got, err := c.given.MarshalText()
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+			if string(got) != c.expected {
+				t.Errorf("expected:\n	%s but got:\n	%s", c.expected, got)
+			}
+		})
+	}
+}
+
+func TestLog_MarshalText_largeBytes(t *testing.T) {
+	expected := Log{Type: "http", ReceivedBytes: 1 << 33, SentBytes: 1<<63 - 1}
+	text, err := expected.MarshalText()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	got, err := Parse(text)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if got.ReceivedBytes != expected.ReceivedBytes || got.SentBytes != expected.SentBytes {
+		t.Errorf("expected %d, %d but got %d, %d", expected.ReceivedBytes, expected.SentBytes, got.ReceivedBytes, got.SentBytes)
+	}
+}
+
+func TestLog_MarshalText_lineBreak(t *testing.T) {
+	l := Log{Type: "http", UserAgent: "curl\n"}
+	if _, err := l.MarshalText(); err != ErrLineBreak {
+		t.Errorf("expected ErrLineBreak, got: %v", err)
+	}
+	b, err := l.AppendText([]byte("prefix"))
+	if err != ErrLineBreak || string(b) != "prefix" {
+		t.Errorf("expected ErrLineBreak and unchanged buffer, got: %v, %s", err, b)
+	}
+}
+
+func TestLog_MarshalText_roundTrip(t *testing.T) {
+	str := func(s string) string {
+		return strings.NewReplacer("\n", "", "\r", "").Replace(s)
+	}
+	duration := func(ns int64) NullDuration {
+		if ns < 0 {
+			return NullDuration{}
+		}
+		return NullDuration{Duration: time.Duration(ns), Valid: true}
+	}
+	f := func(typ, name, request, userAgent, traceID, reason, other string, ns, received int32, sec uint32, code uint16, ip [16]byte, port uint16) bool {
+		expected := Log{
+			Type:                  str(typ),
+			Time:                  time.Unix(int64(sec), int64(ns)).UTC(),
+			Name:                  str(name),
+			From:                  &net.TCPAddr{IP: ip[:], Port: int(port)},
+			RequestProcessingTime: duration(int64(ns)),
+			ELBStatusCode:         int(code),
+			ReceivedBytes:         int64(received),
+			Request:               str(request),
+			UserAgent:             str(userAgent),
+			TraceID:               str(traceID),
+			ClassificationReason:  str(reason),
+		}
+		if code%2 == 0 {
+			expected.BackendStatusCode = NullInt{Int: int(code), Valid: true}
+		}
+		expected.RequestLine = ParseRequestLine(expected.Request)
+		if other = str(other); expected.ClassificationReason != "" && strings.TrimLeft(other, " ") != "" {
+			expected.OtherFields = other
+			expected.TrailingFields, expected.UnknownFields = parseTrailingFields([]byte(other), newLineStrings([]byte(other), false), DefaultTrailingFields, nil)
+		}
+		switch {
+		case expected.ClassificationReason != "":
+			expected.Version = Version2020
+		case expected.TraceID != "":
+			expected.Version = Version2017
+		default:
+			expected.Version = Version2015
+		}
+
+		text, err := expected.MarshalText()
+		if err != nil {
+			t.Log(err)
+			return false
+		}
+		got, err := Parse(text)
+		if err != nil {
+			t.Logf("%s: %v", text, err)
+			return false
+		}
+		if !reflect.DeepEqual(*got, expected) {
+			t.Logf("expected:\n	%v but got:\n	%v", expected, *got)
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(f, &quick.Config{MaxCount: 1000}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEncoder_Encode(t *testing.T) {
+	data, err := os.ReadFile("data.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected []*Log
+	for log, err := range All(bytes.NewReader(data)) {
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		expected = append(expected, log)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	enc := NewEncoder(buf)
+	for _, log := range expected {
+		if err := enc.Encode(log); err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+	}
+	var got []*Log
+	for log, err := range All(buf) {
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		got = append(got, log)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("decoded logs are different than encoded ones")
+	}
+}
@@ -0,0 +1,102 @@
+package elblog
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NoRulePriority is returned by RulePriority if no rule matched the request.
+const NoRulePriority = -1
+
+// CreationTime returns RequestCreationTime as time.Time.
+// It returns the zero time if the field is "-" or missing (logs older than June 2019).
+func (l *Log) CreationTime() (time.Time, error) {
+	if isEmptyField(l.RequestCreationTime) {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339Nano, l.RequestCreationTime)
+}
+
+// RulePriority returns MatchedRulePriority as int. The default rule has priority 0.
+// It returns NoRulePriority if the field is "-" or missing.
+func (l *Log) RulePriority() (int, error) {
+	if isEmptyField(l.MatchedRulePriority) {
+		return NoRulePriority, nil
+	}
+	return strconv.Atoi(l.MatchedRulePriority)
+}
+
+// Actions returns ActionsExecuted split into separate actions, in the order they were taken.
+// It returns nil if the field is "-" or missing.
+func (l *Log) Actions() []string {
+	if isEmptyField(l.ActionsExecuted) {
+		return nil
+	}
+	return strings.Split(l.ActionsExecuted, ",")
+}
+
+// Targets returns TargetPortList as a list of addresses. It returns nil if the field is "-" or missing.
+func (l *Log) Targets() ([]netip.AddrPort, error) {
+	if isEmptyField(l.TargetPortList) {
+		return nil, nil
+	}
+	fields := strings.Fields(l.TargetPortList)
+	targets := make([]netip.AddrPort, 0, len(fields))
+	for _, f := range fields {
+		host, port, err := splitHostPort([]byte(f))
+		if err != nil {
+			return nil, err
+		}
+		addr, err := netip.ParseAddr(string(host))
+		if err != nil {
+			return nil, err
+		}
+		if port == nil {
+			return nil, fmt.Errorf("missing port in address %q", f)
+		}
+		p, err := strconv.ParseUint(string(port), 10, 16)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, netip.AddrPortFrom(addr, uint16(p)))
+	}
+	return targets, nil
+}
+
+// TargetStatusCodes returns TargetStatusCodeList as a list of status codes, in the same order as Targets.
+// Targets that did not respond are represented by 0. It returns nil if the field is "-" or missing.
+func (l *Log) TargetStatusCodes() ([]int, error) {
+	if isEmptyField(l.TargetStatusCodeList) {
+		return nil, nil
+	}
+	fields := strings.Fields(l.TargetStatusCodeList)
+	codes := make([]int, 0, len(fields))
+	for _, f := range fields {
+		if f == "-" {
+			codes = append(codes, 0)
+			continue
+		}
+		code, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// ConnTraceID returns conn_trace_id trailing field, which identifies the connection in ALB connection logs.
+// It returns an empty string if the field is "-" or missing.
+func (l *Log) ConnTraceID() string {
+	if id := l.TrailingFields["conn_trace_id"]; !isEmptyField(id) {
+		return id
+	}
+	return ""
+}
+
+func isEmptyField(s string) bool {
+	return s == "" || s == "-"
+}
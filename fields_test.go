@@ -0,0 +1,164 @@
+package elblog
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLog_CreationTime(t *testing.T) {
+	cases := map[string]struct {
+		given    string
+		expected time.Time
+		err      bool
+	}{
+		"valid":   {given: "2018-07-02T22:22:48.364000Z", expected: time.Date(2018, 7, 2, 22, 22, 48, 364000000, time.UTC)},
+		"dash":    {given: "-"},
+		"missing": {given: ""},
+		"invalid": {given: "yesterday", err: true},
+	}
+
+	for hint, c := range cases {
+		t.Run(hint, func(t *testing.T) {
+			got, err := (&Log{RequestCreationTime: c.given}).CreationTime()
+			if (err != nil) != c.err {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !got.Equal(c.expected) {
+				t.Errorf("expected %v but got %v", c.expected, got)
+			}
+		})
+	}
+}
+
+func TestLog_RulePriority(t *testing.T) {
+	cases := map[string]struct {
+		given    string
+		expected int
+		err      bool
+	}{
+		"default": {given: "0", expected: 0},
+		"rule":    {given: "10", expected: 10},
+		"dash":    {given: "-", expected: NoRulePriority},
+		"missing": {given: "", expected: NoRulePriority},
+		"invalid": {given: "high", err: true},
+	}
+
+	for hint, c := range cases {
+		t.Run(hint, func(t *testing.T) {
+			got, err := (&Log{MatchedRulePriority: c.given}).RulePriority()
+			if (err != nil) != c.err {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err == nil && got != c.expected {
+				t.Errorf("expected %d but got %d", c.expected, got)
+			}
+		})
+	}
+}
+
+func TestLog_Actions(t *testing.T) {
+	cases := map[string]struct {
+		given    string
+		expected []string
+	}{
+		"single":   {given: "forward", expected: []string{"forward"}},
+		"multiple": {given: "waf,authenticate,forward", expected: []string{"waf", "authenticate", "forward"}},
+		"dash":     {given: "-"},
+		"missing":  {given: ""},
+	}
+
+	for hint, c := range cases {
+		t.Run(hint, func(t *testing.T) {
+			got := (&Log{ActionsExecuted: c.given}).Actions()
+			if !reflect.DeepEqual(got, c.expected) {
+				t.Errorf("expected %q but got %q", c.expected, got)
+			}
+		})
+	}
+}
+
+func TestLog_Targets(t *testing.T) {
+	cases := map[string]struct {
+		given    string
+		expected []netip.AddrPort
+		err      bool
+	}{
+		"single": {
+			given:    "10.0.0.1:80",
+			expected: []netip.AddrPort{netip.MustParseAddrPort("10.0.0.1:80")},
+		},
+		"multiple": {
+			given: "10.0.0.1:80 [2001:db8::1]:8080 2001:db8::2:8081",
+			expected: []netip.AddrPort{
+				netip.MustParseAddrPort("10.0.0.1:80"),
+				netip.MustParseAddrPort("[2001:db8::1]:8080"),
+				netip.MustParseAddrPort("[2001:db8::2]:8081"),
+			},
+		},
+		"dash":         {given: "-"},
+		"missing":      {given: ""},
+		"invalid-ip":   {given: "localhost:80", err: true},
+		"missing-port": {given: "10.0.0.1", err: true},
+		"invalid-port": {given: "10.0.0.1:99999", err: true},
+	}
+
+	for hint, c := range cases {
+		t.Run(hint, func(t *testing.T) {
+			got, err := (&Log{TargetPortList: c.given}).Targets()
+			if (err != nil) != c.err {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, c.expected) {
+				t.Errorf("expected %v but got %v", c.expected, got)
+			}
+		})
+	}
+}
+
+func TestLog_TargetStatusCodes(t *testing.T) {
+	cases := map[string]struct {
+		given    string
+		expected []int
+		err      bool
+	}{
+		"single":        {given: "200", expected: []int{200}},
+		"multiple":      {given: "502 200", expected: []int{502, 200}},
+		"no-response":   {given: "- 200", expected: []int{0, 200}},
+		"dash":          {given: "-"},
+		"missing":       {given: ""},
+		"invalid-value": {given: "OK", err: true},
+	}
+
+	for hint, c := range cases {
+		t.Run(hint, func(t *testing.T) {
+			got, err := (&Log{TargetStatusCodeList: c.given}).TargetStatusCodes()
+			if (err != nil) != c.err {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, c.expected) {
+				t.Errorf("expected %v but got %v", c.expected, got)
+			}
+		})
+	}
+}
+
+func TestLog_ConnTraceID(t *testing.T) {
+	cases := map[string]struct {
+		given    map[string]string
+		expected string
+	}{
+		"valid":   {given: map[string]string{"conn_trace_id": "TID_1234"}, expected: "TID_1234"},
+		"dash":    {given: map[string]string{"conn_trace_id": "-"}},
+		"missing": {given: nil},
+	}
+
+	for hint, c := range cases {
+		t.Run(hint, func(t *testing.T) {
+			if got := (&Log{TrailingFields: c.given}).ConnTraceID(); got != c.expected {
+				t.Errorf("expected %q but got %q", c.expected, got)
+			}
+		})
+	}
+}
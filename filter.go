@@ -0,0 +1,477 @@
+package elblog
+
+import (
+	"cmp"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Filter is a compiled filter expression, see Compile. It is safe for concurrent use.
+type Filter struct {
+	expr  string
+	match func(*Log) bool
+}
+
+// Compile compiles a filter expression, e.g. elb_status >= 500 and domain == "api.example.com" and target_time > 2s.
+//
+// An expression is made of comparisons of a field with a literal, combined with and, or, not and parentheses.
+// Comparison operators are ==, !=, <, <=, >, >= and contains, which is for strings only.
+// Literals are strings in double quotes with Go escapes, numbers and durations like 150ms or 2s.
+// Durations may be compared with numbers of seconds as well, time is compared with RFC 3339 strings.
+//
+// Fields are named after ALB access log fields, e.g. elb_status_code, target_processing_time or domain_name, which have
+// shorter aliases like elb_status, target_time or domain. Parts of the request line are method, url and proto.
+// Fields that are not available, like processing time logged as -1 or target status code logged as -,
+// match only the != operator.
+func Compile(expr string) (*Filter, error) {
+	p := &filterParser{s: expr}
+	p.next()
+	match, err := p.parseOr()
+	if err == nil && p.tok.kind != tokenEOF {
+		err = p.errorf("unexpected %s", p.tok)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Filter{expr: expr, match: match}, nil
+}
+
+// MustCompile is like Compile but panics if the expression cannot be compiled.
+func MustCompile(expr string) *Filter {
+	f, err := Compile(expr)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// Match returns true if the log matches the expression.
+func (f *Filter) Match(l *Log) bool {
+	return f.match(l)
+}
+
+// String returns the source of the expression.
+func (f *Filter) String() string {
+	return f.expr
+}
+
+// WithFilter makes Decoder return only logs matching the filter. Lines that do not match are not reported
+// in any way, lines that cannot be parsed are handled according to the error policy.
+func WithFilter(f *Filter) DecoderOption {
+	return func(d *Decoder) {
+		d.filter = f
+	}
+}
+
+type fieldKind int
+
+const (
+	kindString fieldKind = iota
+	kindNumber
+	kindDuration
+	kindTime
+)
+
+func (k fieldKind) String() string {
+	switch k {
+	case kindNumber:
+		return "number"
+	case kindDuration:
+		return "duration"
+	case kindTime:
+		return "time"
+	default:
+		return "string"
+	}
+}
+
+// filterField gets the value of a field of given kind, ok is false if the value is not available.
+type filterField struct {
+	kind  fieldKind
+	value func(l *Log) (v any, ok bool)
+}
+
+func stringField(fn func(*Log) string) filterField {
+	return filterField{kind: kindString, value: func(l *Log) (any, bool) {
+		return fn(l), true
+	}}
+}
+
+func numberField(fn func(*Log) (int64, bool)) filterField {
+	return filterField{kind: kindNumber, value: func(l *Log) (any, bool) {
+		n, ok := fn(l)
+		return float64(n), ok
+	}}
+}
+
+func durationField(fn func(*Log) NullDuration) filterField {
+	return filterField{kind: kindDuration, value: func(l *Log) (any, bool) {
+		d := fn(l)
+		return d.Duration, d.Valid
+	}}
+}
+
+// filterFields are fields that filter expressions can refer to.
+var filterFields = map[string]filterField{
+	"type": stringField(func(l *Log) string { return l.Type }),
+	"time": {kind: kindTime, value: func(l *Log) (any, bool) {
+		return l.Time, !l.Time.IsZero()
+	}},
+	"elb":         stringField(func(l *Log) string { return l.Name }),
+	"client":      stringField(func(l *Log) string { return addrString(l.From) }),
+	"client_ip":   stringField(func(l *Log) string { return addrIP(l.From) }),
+	"client_port": numberField(func(l *Log) (int64, bool) { return addrPort(l.From) }),
+	"target":      stringField(func(l *Log) string { return addrString(l.To) }),
+	"target_ip":   stringField(func(l *Log) string { return addrIP(l.To) }),
+	"target_port": numberField(func(l *Log) (int64, bool) { return addrPort(l.To) }),
+
+	"request_time":  durationField(func(l *Log) NullDuration { return l.RequestProcessingTime }),
+	"target_time":   durationField(func(l *Log) NullDuration { return l.BackendProcessingTime }),
+	"response_time": durationField(func(l *Log) NullDuration { return l.ResponseProcessingTime }),
+	"elb_status": numberField(func(l *Log) (int64, bool) {
+		return int64(l.ELBStatusCode), l.ELBStatusCode != 0
+	}),
+	"target_status": numberField(func(l *Log) (int64, bool) {
+		return int64(l.BackendStatusCode.Int), l.BackendStatusCode.Valid
+	}),
+	"received_bytes": numberField(func(l *Log) (int64, bool) { return l.ReceivedBytes, true }),
+	"sent_bytes":     numberField(func(l *Log) (int64, bool) { return l.SentBytes, true }),
+
+	"request":      stringField(func(l *Log) string { return l.Request }),
+	"method":       stringField(func(l *Log) string { return l.RequestLine.Method }),
+	"url":          stringField(func(l *Log) string { return l.RequestLine.RequestURI }),
+	"proto":        stringField(func(l *Log) string { return l.RequestLine.Proto }),
+	"user_agent":   stringField(func(l *Log) string { return l.UserAgent }),
+	"ssl_cipher":   stringField(func(l *Log) string { return l.SSLCipher }),
+	"ssl_protocol": stringField(func(l *Log) string { return l.SSLProtocol }),
+	"target_group": stringField(func(l *Log) string { return l.TargetGroupARN }),
+	"target_group_name": stringField(func(l *Log) string {
+		tg, _ := l.TargetGroup()
+		return tg.Name
+	}),
+	"trace_id":                stringField(func(l *Log) string { return l.TraceID }),
+	"domain":                  stringField(func(l *Log) string { return l.DomainName }),
+	"chosen_cert_arn":         stringField(func(l *Log) string { return l.ChosenCertARN }),
+	"matched_rule_priority":   stringField(func(l *Log) string { return l.MatchedRulePriority }),
+	"request_creation_time":   stringField(func(l *Log) string { return l.RequestCreationTime }),
+	"actions":                 stringField(func(l *Log) string { return l.ActionsExecuted }),
+	"redirect_url":            stringField(func(l *Log) string { return l.RedirectURL }),
+	"error_reason":            stringField(func(l *Log) string { return string(l.ErrorReason) }),
+	"target_port_list":        stringField(func(l *Log) string { return l.TargetPortList }),
+	"target_status_code_list": stringField(func(l *Log) string { return l.TargetStatusCodeList }),
+	"classification":          stringField(func(l *Log) string { return string(l.Classification) }),
+	"classification_reason":   stringField(func(l *Log) string { return l.ClassificationReason }),
+	"conn_trace_id":           stringField(func(l *Log) string { return l.ConnTraceID() }),
+	"version":                 stringField(func(l *Log) string { return l.Version.String() }),
+}
+
+// filterAliases maps names of access log fields and Log fields to names used by filterFields.
+var filterAliases = map[string]string{
+	"timestamp":                "time",
+	"name":                     "elb",
+	"client_addr":              "client",
+	"target_addr":              "target",
+	"request_processing_time":  "request_time",
+	"target_processing_time":   "target_time",
+	"backend_processing_time":  "target_time",
+	"response_processing_time": "response_time",
+	"elb_status_code":          "elb_status",
+	"target_status_code":       "target_status",
+	"backend_status_code":      "target_status",
+	"uri":                      "url",
+	"target_group_arn":         "target_group",
+	"domain_name":              "domain",
+	"actions_executed":         "actions",
+}
+
+func addrString(addr *net.TCPAddr) string {
+	if addr == nil {
+		return "-"
+	}
+	return addr.String()
+}
+
+func addrIP(addr *net.TCPAddr) string {
+	if addr == nil {
+		return "-"
+	}
+	return addr.IP.String()
+}
+
+func addrPort(addr *net.TCPAddr) (int64, bool) {
+	if addr == nil {
+		return 0, false
+	}
+	return int64(addr.Port), true
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenDuration
+	tokenOperator
+	tokenLeftParen
+	tokenRightParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of expression"
+	}
+	return strconv.Quote(t.text)
+}
+
+// filterParser is a recursive descent parser of filter expressions:
+//
+//	or         = and { "or" and }
+//	and        = not { "and" not }
+//	not        = "not" not | "(" or ")" | comparison
+//	comparison = field operator literal
+type filterParser struct {
+	s   string
+	pos int
+	tok token
+}
+
+func (p *filterParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("filter %q, position %d: %s", p.s, p.tok.pos+1, fmt.Sprintf(format, args...))
+}
+
+// next scans the next token, an invalid one is reported by the parsing function that consumes it.
+func (p *filterParser) next() {
+	for p.pos < len(p.s) && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t' || p.s[p.pos] == '\n' || p.s[p.pos] == '\r') {
+		p.pos++
+	}
+	start := p.pos
+	if p.pos == len(p.s) {
+		p.tok = token{kind: tokenEOF, pos: start}
+		return
+	}
+	kind := tokenOperator
+	switch c := p.s[p.pos]; {
+	case c == '(':
+		kind = tokenLeftParen
+		p.pos++
+	case c == ')':
+		kind = tokenRightParen
+		p.pos++
+	case c == '"':
+		kind = tokenString
+		p.pos++
+		for p.pos < len(p.s) && p.s[p.pos] != '"' {
+			if p.s[p.pos] == '\\' {
+				p.pos++
+			}
+			p.pos++
+		}
+		p.pos = min(p.pos+1, len(p.s))
+	case c == '=' || c == '!' || c == '<' || c == '>':
+		p.pos++
+		if p.pos < len(p.s) && p.s[p.pos] == '=' {
+			p.pos++
+		}
+	case c == '-' || c == '.' || '0' <= c && c <= '9':
+		kind = tokenNumber
+		p.pos++
+		for p.pos < len(p.s) && strings.IndexByte(" \t\r\n()=!<>\"", p.s[p.pos]) < 0 {
+			p.pos++
+		}
+		if _, err := strconv.ParseFloat(p.s[start:p.pos], 64); err != nil {
+			kind = tokenDuration
+		}
+	case c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z':
+		kind = tokenIdent
+		for p.pos < len(p.s) && (p.s[p.pos] == '_' || 'a' <= p.s[p.pos] && p.s[p.pos] <= 'z' ||
+			'A' <= p.s[p.pos] && p.s[p.pos] <= 'Z' || '0' <= p.s[p.pos] && p.s[p.pos] <= '9') {
+			p.pos++
+		}
+	default:
+		p.pos++
+	}
+	p.tok = token{kind: kind, text: p.s[start:p.pos], pos: start}
+}
+
+func (p *filterParser) keyword(s string) bool {
+	return p.tok.kind == tokenIdent && p.tok.text == s
+}
+
+func (p *filterParser) parseOr() (func(*Log) bool, error) {
+	left, err := p.parseAnd()
+	for err == nil && p.keyword("or") {
+		p.next()
+		var right func(*Log) bool
+		if right, err = p.parseAnd(); err == nil {
+			left = or(left, right)
+		}
+	}
+	return left, err
+}
+
+func or(left, right func(*Log) bool) func(*Log) bool {
+	return func(l *Log) bool { return left(l) || right(l) }
+}
+
+func (p *filterParser) parseAnd() (func(*Log) bool, error) {
+	left, err := p.parseNot()
+	for err == nil && p.keyword("and") {
+		p.next()
+		var right func(*Log) bool
+		if right, err = p.parseNot(); err == nil {
+			left = and(left, right)
+		}
+	}
+	return left, err
+}
+
+func and(left, right func(*Log) bool) func(*Log) bool {
+	return func(l *Log) bool { return left(l) && right(l) }
+}
+
+func (p *filterParser) parseNot() (func(*Log) bool, error) {
+	switch {
+	case p.keyword("not"):
+		p.next()
+		match, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return func(l *Log) bool { return !match(l) }, nil
+	case p.tok.kind == tokenLeftParen:
+		p.next()
+		match, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokenRightParen {
+			return nil, p.errorf("expected ) but got %s", p.tok)
+		}
+		p.next()
+		return match, nil
+	default:
+		return p.parseComparison()
+	}
+}
+
+func (p *filterParser) parseComparison() (func(*Log) bool, error) {
+	if p.tok.kind != tokenIdent {
+		return nil, p.errorf("expected field but got %s", p.tok)
+	}
+	name := p.tok.text
+	if alias, ok := filterAliases[name]; ok {
+		name = alias
+	}
+	field, ok := filterFields[name]
+	if !ok {
+		return nil, p.errorf("unknown field %s", p.tok)
+	}
+	p.next()
+
+	op := p.tok.text
+	switch {
+	case p.tok.kind == tokenOperator && (op == "==" || op == "!=" || op == "<" || op == "<=" || op == ">" || op == ">="):
+	case p.keyword("contains") && field.kind == kindString:
+	default:
+		return nil, p.errorf("expected %s comparison operator but got %s", field.kind, p.tok)
+	}
+	p.next()
+
+	value, err := p.literal(field.kind)
+	if err != nil {
+		return nil, err
+	}
+	p.next()
+	if op == "contains" {
+		s := value.(string)
+		return func(l *Log) bool {
+			v, _ := field.value(l)
+			return strings.Contains(v.(string), s)
+		}, nil
+	}
+	return func(l *Log) bool {
+		v, ok := field.value(l)
+		if !ok {
+			return op == "!="
+		}
+		return compare(v, value, op)
+	}, nil
+}
+
+// literal returns the value of the current token converted to given kind.
+func (p *filterParser) literal(kind fieldKind) (any, error) {
+	switch tok := p.tok; {
+	case tok.kind == tokenString && kind == kindString:
+		s, err := strconv.Unquote(tok.text)
+		if err != nil {
+			return nil, p.errorf("invalid string %s", tok)
+		}
+		return s, nil
+	case tok.kind == tokenString && kind == kindTime:
+		s, err := strconv.Unquote(tok.text)
+		if err != nil {
+			return nil, p.errorf("invalid string %s", tok)
+		}
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, p.errorf("invalid time %s", tok)
+		}
+		return t, nil
+	case tok.kind == tokenNumber && kind == kindNumber:
+		n, _ := strconv.ParseFloat(tok.text, 64)
+		return n, nil
+	case tok.kind == tokenNumber && kind == kindDuration:
+		n, _ := strconv.ParseFloat(tok.text, 64)
+		return time.Duration(n * float64(time.Second)), nil
+	case tok.kind == tokenDuration && kind == kindDuration:
+		d, err := time.ParseDuration(tok.text)
+		if err != nil {
+			return nil, p.errorf("invalid duration %s", tok)
+		}
+		return d, nil
+	case tok.kind == tokenDuration:
+		return nil, p.errorf("invalid number %s", tok)
+	default:
+		return nil, p.errorf("expected %s but got %s", kind, tok)
+	}
+}
+
+// compare compares values of the same kind.
+func compare(a, b any, op string) bool {
+	var c int
+	switch a := a.(type) {
+	case string:
+		c = strings.Compare(a, b.(string))
+	case float64:
+		c = cmp.Compare(a, b.(float64))
+	case time.Duration:
+		c = cmp.Compare(a, b.(time.Duration))
+	case time.Time:
+		c = a.Compare(b.(time.Time))
+	}
+	switch op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	default:
+		return c >= 0
+	}
+}
@@ -0,0 +1,115 @@
+package elblog
+
+import (
+	"strings"
+	"testing"
+)
+
+const filterLine = `https 2018-07-02T22:23:00.186641Z app/my-loadbalancer/50dc6c495c0c9188 192.168.131.39:2817 - 0.086 -1 -1 502 - 34 366 "GET https://api.example.com:443/users?id=1 HTTP/1.1" "curl/7.46.0" ECDHE-RSA-AES128-GCM-SHA256 TLSv1.2 arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067 "Root=1-58337281-1d84f3d73c47ec4e58577259" "api.example.com" "session-reused" 0 2018-07-02T22:22:48.364000Z "forward" "-" "LambdaUnhandled" "-" "-" "-" "-"`
+
+func TestFilter_Match(t *testing.T) {
+	log, err := Parse([]byte(filterLine))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	cases := map[string]struct {
+		given    string
+		expected bool
+	}{
+		"status":                {given: `elb_status >= 500`, expected: true},
+		"status-alias":          {given: `elb_status_code == 502`, expected: true},
+		"status-mismatch":       {given: `elb_status < 500`},
+		"domain":                {given: `domain == "api.example.com"`, expected: true},
+		"domain-mismatch":       {given: `domain_name != "api.example.com"`},
+		"duration":              {given: `request_time > 50ms`, expected: true},
+		"duration-seconds":      {given: `request_time < 0.1`, expected: true},
+		"not-available":         {given: `target_time > 2s or target_time <= 2s`},
+		"not-available-ne":      {given: `target_time != 2s`, expected: true},
+		"target-status-missing": {given: `target_status == 200`},
+		"contains":              {given: `url contains "/users"`, expected: true},
+		"method":                {given: `method == "GET" and proto == "HTTP/1.1"`, expected: true},
+		"target-group":          {given: `target_group_name == "my-targets"`, expected: true},
+		"error-reason":          {given: `error_reason == "LambdaUnhandled"`, expected: true},
+		"time":                  {given: `time >= "2018-07-02T22:00:00Z" and time < "2018-07-03T00:00:00Z"`, expected: true},
+		"escapes":               {given: `user_agent == "curl\x2f7.46.0"`, expected: true},
+		"not":                   {given: `not elb_status == 200`, expected: true},
+		"precedence":            {given: `elb_status == 200 and sent_bytes > 0 or domain == "api.example.com"`, expected: true},
+		"parentheses":           {given: `elb_status == 200 and (sent_bytes > 0 or domain == "api.example.com")`},
+		"slow":                  {given: `elb_status >= 500 and domain == "api.example.com" and request_time > 2s`},
+	}
+
+	for hint, c := range cases {
+		t.Run(hint, func(t *testing.T) {
+			f, err := Compile(c.given)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+			if got := f.Match(log); got != c.expected {
+				t.Errorf("expected %t but got %t", c.expected, got)
+			}
+		})
+	}
+}
+
+func TestCompile_errors(t *testing.T) {
+	cases := map[string]string{
+		"empty":             ``,
+		"unknown-field":     `status == 200`,
+		"missing-operator":  `elb_status 200`,
+		"missing-literal":   `elb_status ==`,
+		"type-mismatch":     `elb_status == "200"`,
+		"string-number":     `domain == 1`,
+		"invalid-duration":  `target_time > 2x`,
+		"number-duration":   `elb_status > 2s`,
+		"contains-number":   `elb_status contains 5`,
+		"invalid-time":      `time > "yesterday"`,
+		"unterminated":      `domain == "api`,
+		"unbalanced":        `(elb_status == 200`,
+		"trailing":          `elb_status == 200 200`,
+		"dangling-operator": `elb_status == 200 and`,
+	}
+
+	for hint, given := range cases {
+		t.Run(hint, func(t *testing.T) {
+			if _, err := Compile(given); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestDecoder_Decode_filter(t *testing.T) {
+	input := strings.Join([]string{validLine, filterLine, invalidLine, filterLine, validLine}, "\n")
+	f := MustCompile(`elb_status >= 500`)
+
+	dec := NewDecoder(strings.NewReader(input), WithFilter(f), WithErrorPolicy(ErrorPolicyCollect))
+	var lines []int
+	for log, err := range dec.All() {
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		if log.ELBStatusCode != 502 {
+			t.Errorf("log should not match the filter: %v", log)
+		}
+		lines = append(lines, dec.Line())
+	}
+	if len(lines) != 2 || lines[0] != 2 || lines[1] != 4 {
+		t.Errorf("wrong lines, expected [2 4] but got %v", lines)
+	}
+	if len(dec.Skipped()) != 1 {
+		t.Errorf("invalid line should be skipped, got %d skipped lines", len(dec.Skipped()))
+	}
+
+	pdec := NewParallelDecoder(strings.NewReader(input), 2, WithFilter(f), WithErrorPolicy(ErrorPolicySkip))
+	var n int
+	for _, err := range pdec.All() {
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		n++
+	}
+	if n != 2 {
+		t.Errorf("wrong number of logs, expected 2 but got %d", n)
+	}
+}
@@ -0,0 +1,44 @@
+package elblog
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+)
+
+// gzipMagic are the bytes every gzip member starts with.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// Decompress returns a reader that transparently decompresses r if it is gzip compressed, e.g. a log object
+// delivered to S3. Compression is detected by the gzip magic bytes, uncompressed input is read as it is.
+// Concatenated gzip members are read as a single stream.
+// It can be used with any decoder, Decoder can do it on its own, see WithGzip.
+func Decompress(r io.Reader) io.Reader {
+	return &sniffReader{
+		br: bufio.NewReader(r),
+	}
+}
+
+// sniffReader checks on the first read whether the input is gzip compressed.
+type sniffReader struct {
+	br *bufio.Reader
+	r  io.Reader
+}
+
+func (s *sniffReader) Read(p []byte) (int, error) {
+	if s.r == nil {
+		// Peek returns less bytes for shorter input, which is not compressed then.
+		magic, _ := s.br.Peek(len(gzipMagic))
+		if bytes.Equal(magic, gzipMagic) {
+			zr, err := gzip.NewReader(s.br)
+			if err != nil {
+				return 0, err
+			}
+			s.r = zr
+		} else {
+			s.r = s.br
+		}
+	}
+	return s.r.Read(p)
+}
@@ -0,0 +1,84 @@
+package elblog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"testing"
+)
+
+func gzipped(t *testing.T, data []byte) []byte {
+	t.Helper()
+	buf := bytes.NewBuffer(nil)
+	zw := gzip.NewWriter(buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecoder_Decode_gzip(t *testing.T) {
+	data, err := os.ReadFile("data.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]struct {
+		given    []byte
+		expected int
+	}{
+		"plain":              {given: data, expected: 7},
+		"gzip":               {given: gzipped(t, data), expected: 7},
+		"concatenated-gzip":  {given: append(gzipped(t, data), gzipped(t, data)...), expected: 14},
+		"empty":              {given: nil, expected: 0},
+		"empty-gzip-members": {given: append(gzipped(t, nil), gzipped(t, data)...), expected: 7},
+	}
+
+	for hint, c := range cases {
+		t.Run(hint, func(t *testing.T) {
+			dec := NewDecoder(bytes.NewReader(c.given), WithGzip(), WithErrorPolicy(ErrorPolicySkip))
+			n := 0
+			for dec.More() {
+				if _, err := dec.Decode(); err != nil {
+					t.Fatalf("unexpected error: %s", err.Error())
+				}
+				n++
+			}
+			if n != c.expected {
+				t.Errorf("wrong number of decoded logs, expected %d but got %d", c.expected, n)
+			}
+			if c.expected > 0 && dec.Offset() != int64(len(data)*c.expected/7) {
+				t.Errorf("offset should refer to decompressed input, got %d", dec.Offset())
+			}
+		})
+	}
+}
+
+func TestDecoder_Decode_corruptedGzip(t *testing.T) {
+	given := gzipped(t, []byte(validLine))
+	given[2] = 0 // unknown compression method
+
+	dec := NewDecoder(bytes.NewReader(given), WithGzip())
+	if _, err := dec.Decode(); err != gzip.ErrHeader {
+		t.Fatalf("expected ErrHeader, got: %v", err)
+	}
+	if _, err := dec.Decode(); err != io.EOF {
+		t.Errorf("expected EOF, got: %v", err)
+	}
+}
+
+func TestDecompress(t *testing.T) {
+	line := []byte(`tls 2.0 2018-12-20T02:59:40 net/my-network-loadbalancer/c6e77e28c25b2234 g3d4b5e8bb8464cd 72.21.218.154:51341 172.100.100.185:443 5 2 98 246 - arn:aws:acm:us-east-2:671290407336:certificate/2a108f19-aded-46b0-8493-c63eb1ef4a99 - ECDHE-RSA-AES128-SHA tlsv12 - my-network-loadbalancer-c6e77e28c25b2234.elb.us-east-2.amazonaws.com - - - 2018-12-20T02:59:30`)
+	dec := NewNLBDecoder(Decompress(bytes.NewReader(gzipped(t, line))))
+	log, err := dec.Decode()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if log.Listener != "g3d4b5e8bb8464cd" {
+		t.Errorf("wrong listener: %s", log.Listener)
+	}
+}
@@ -0,0 +1,183 @@
+package elblog
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// NLBLog is a single entry of Network Load Balancer access log. Network Load Balancer writes logs only for TLS listeners:
+// https://docs.aws.amazon.com/elasticloadbalancing/latest/network/load-balancer-access-logs.html
+type NLBLog struct {
+	Type                     string
+	Version                  string
+	Time                     time.Time
+	Name                     string
+	Listener                 string
+	From, To                 *net.TCPAddr
+	ConnectionTime           time.Duration
+	TLSHandshakeTime         NullDuration
+	ReceivedBytes            int64
+	SentBytes                int64
+	IncomingTLSAlert         string
+	ChosenCertARN            string
+	ChosenCertSerial         string
+	TLSCipher                string
+	TLSProtocolVersion       string
+	TLSNamedGroup            string
+	DomainName               string
+	ALPNFrontendProtocol     string
+	ALPNBackendProtocol      string
+	ALPNClientPreferenceList []string
+	ConnectionCreationTime   time.Time
+	OtherFields              string
+}
+
+const numNLBTokens = 23
+
+// nlbFieldNames are the names of NLB log fields, as in AWS documentation.
+var nlbFieldNames = []string{
+	"type", "version", "time", "elb", "listener", "client:port", "destination:port",
+	"connection_time", "tls_handshake_time", "received_bytes", "sent_bytes", "incoming_tls_alert",
+	"chosen_cert_arn", "chosen_cert_serial", "tls_cipher", "tls_protocol_version", "tls_named_group",
+	"domain_name", "alpn_fe_protocol", "alpn_be_protocol", "alpn_client_preference_list",
+	"tls_connection_creation_time", "other_fields",
+}
+
+// nlbTimeLayout is the layout of timestamps in NLB logs, they are in UTC but have no zone designator.
+const nlbTimeLayout = "2006-01-02T15:04:05"
+
+// ParseNLB parses single line of Network Load Balancer access log.
+// TLSHandshakeTime is not valid if the handshake was not completed.
+func ParseNLB(b []byte) (*NLBLog, error) {
+	var (
+		adv, i int
+		ms     float64
+		err    error
+		tok    []byte
+	)
+
+	data := b
+	log := &NLBLog{}
+	for i < numNLBTokens && adv < len(data) {
+		data = data[adv:]
+		adv, tok, err = scan(data)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan next token: %v", err)
+		}
+		switch i {
+		case 0:
+			log.Type = string(tok)
+		case 1:
+			log.Version = string(tok)
+		case 2:
+			log.Time, err = parseNLBTime(tok)
+		case 3:
+			log.Name = string(tok)
+		case 4:
+			log.Listener = string(tok)
+		case 5:
+			log.From, err = parseAddr(tok, nil)
+		case 6:
+			log.To, err = parseAddr(tok, nil)
+		case 7:
+			ms, err = strconv.ParseFloat(string(tok), 64)
+			log.ConnectionTime = time.Duration(ms * 1000 * 1000)
+		case 8:
+			if !isDash(tok) {
+				ms, err = strconv.ParseFloat(string(tok), 64)
+				log.TLSHandshakeTime = NullDuration{Duration: time.Duration(ms * 1000 * 1000), Valid: true}
+			}
+		case 9:
+			log.ReceivedBytes, err = strconv.ParseInt(string(tok), 10, 64)
+		case 10:
+			log.SentBytes, err = strconv.ParseInt(string(tok), 10, 64)
+		case 11:
+			log.IncomingTLSAlert = string(tok)
+		case 12:
+			log.ChosenCertARN = string(tok)
+		case 13:
+			log.ChosenCertSerial = string(tok)
+		case 14:
+			log.TLSCipher = string(tok)
+		case 15:
+			log.TLSProtocolVersion = string(tok)
+		case 16:
+			log.TLSNamedGroup = string(tok)
+		case 17:
+			log.DomainName = string(tok)
+		case 18:
+			log.ALPNFrontendProtocol = string(tok)
+		case 19:
+			log.ALPNBackendProtocol = string(tok)
+		case 20:
+			log.ALPNClientPreferenceList = parseALPNList(tok)
+		case 21:
+			log.ConnectionCreationTime, err = parseNLBTime(tok)
+		case 22:
+			// everything remaining goes into OtherFields, the same way as for ALB logs
+			log.OtherFields = string(data)
+			adv = len(data)
+		}
+		if err != nil {
+			return nil, newParseError(b, data, tok, i, nlbFieldNames, err)
+		}
+		i++
+	}
+	return log, nil
+}
+
+func parseNLBTime(tok []byte) (time.Time, error) {
+	if t, err := time.Parse(nlbTimeLayout, string(tok)); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339Nano, string(tok))
+}
+
+// parseALPNList parses list of quoted protocols, e.g. "h2","http/1.1".
+// Outer quotes are already removed by scan.
+func parseALPNList(tok []byte) []string {
+	if isDash(tok) || len(tok) == 0 {
+		return nil
+	}
+	parts := bytes.Split(tok, []byte(`","`))
+	list := make([]string, 0, len(parts))
+	for _, p := range parts {
+		list = append(list, string(bytes.Trim(p, `"`)))
+	}
+	return list
+}
+
+// NLBDecoder reads and parses Network Load Balancer logs from an input stream.
+type NLBDecoder struct {
+	lines *lineScanner
+}
+
+// NewNLBDecoder allocates new NLBDecoder object for given input.
+func NewNLBDecoder(r io.Reader) *NLBDecoder {
+	return &NLBDecoder{
+		lines: newLineScanner(r),
+	}
+}
+
+// Decode scans input and parse into NLBLog. It returns EOF if there is nothing left to read.
+// Read errors are returned once, then Decode returns EOF.
+func (d *NLBDecoder) Decode() (*NLBLog, error) {
+	b, ok := d.lines.next()
+	if !ok {
+		if err := d.lines.readErr(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	return ParseNLB(b)
+}
+
+// More return true if token is not empty, underlying scanner Scan method will return true
+// or there is a read error to return.
+func (d *NLBDecoder) More() bool {
+	return d.lines.more() || d.lines.failed()
+}
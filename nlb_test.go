@@ -0,0 +1,159 @@
+package elblog
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestParseNLB(t *testing.T) {
+	cases := map[string]struct {
+		given    string
+		expected NLBLog
+	}{
+		"basic": {
+			given: `tls 2.0 2018-12-20T02:59:40 net/my-network-loadbalancer/c6e77e28c25b2234 g3d4b5e8bb8464cd 72.21.218.154:51341 172.100.100.185:443 5 2 98 246 - arn:aws:acm:us-east-2:671290407336:certificate/2a108f19-aded-46b0-8493-c63eb1ef4a99 - ECDHE-RSA-AES128-SHA tlsv12 - my-network-loadbalancer-c6e77e28c25b2234.elb.us-east-2.amazonaws.com - - - 2018-12-20T02:59:30`,
+			expected: NLBLog{
+				Type:     "tls",
+				Version:  "2.0",
+				Time:     time.Date(2018, 12, 20, 2, 59, 40, 0, time.UTC),
+				Name:     "net/my-network-loadbalancer/c6e77e28c25b2234",
+				Listener: "g3d4b5e8bb8464cd",
+				From: &net.TCPAddr{
+					IP:   net.ParseIP("72.21.218.154"),
+					Port: 51341,
+				},
+				To: &net.TCPAddr{
+					IP:   net.ParseIP("172.100.100.185"),
+					Port: 443,
+				},
+				ConnectionTime:         5 * time.Millisecond,
+				TLSHandshakeTime:       NullDuration{Duration: 2 * time.Millisecond, Valid: true},
+				ReceivedBytes:          98,
+				SentBytes:              246,
+				IncomingTLSAlert:       "-",
+				ChosenCertARN:          "arn:aws:acm:us-east-2:671290407336:certificate/2a108f19-aded-46b0-8493-c63eb1ef4a99",
+				ChosenCertSerial:       "-",
+				TLSCipher:              "ECDHE-RSA-AES128-SHA",
+				TLSProtocolVersion:     "tlsv12",
+				TLSNamedGroup:          "-",
+				DomainName:             "my-network-loadbalancer-c6e77e28c25b2234.elb.us-east-2.amazonaws.com",
+				ALPNFrontendProtocol:   "-",
+				ALPNBackendProtocol:    "-",
+				ConnectionCreationTime: time.Date(2018, 12, 20, 2, 59, 30, 0, time.UTC),
+			},
+		},
+		"alpn": {
+			given: `tls 2.0 2020-04-01T08:51:42 net/my-network-loadbalancer/c6e77e28c25b2234 g3d4b5e8bb8464cd 72.21.218.154:51341 172.100.100.185:443 1 - 98 246 - arn:aws:acm:us-east-2:671290407336:certificate/2a108f19-aded-46b0-8493-c63eb1ef4a99 - ECDHE-RSA-AES128-SHA tlsv12 - my-network-loadbalancer-c6e77e28c25b2234.elb.us-east-2.amazonaws.com h2 h2 "h2","http/1.1" 2020-04-01T08:51:20 future-field`,
+			expected: NLBLog{
+				Type:     "tls",
+				Version:  "2.0",
+				Time:     time.Date(2020, 4, 1, 8, 51, 42, 0, time.UTC),
+				Name:     "net/my-network-loadbalancer/c6e77e28c25b2234",
+				Listener: "g3d4b5e8bb8464cd",
+				From: &net.TCPAddr{
+					IP:   net.ParseIP("72.21.218.154"),
+					Port: 51341,
+				},
+				To: &net.TCPAddr{
+					IP:   net.ParseIP("172.100.100.185"),
+					Port: 443,
+				},
+				ConnectionTime:           1 * time.Millisecond,
+				ReceivedBytes:            98,
+				SentBytes:                246,
+				IncomingTLSAlert:         "-",
+				ChosenCertARN:            "arn:aws:acm:us-east-2:671290407336:certificate/2a108f19-aded-46b0-8493-c63eb1ef4a99",
+				ChosenCertSerial:         "-",
+				TLSCipher:                "ECDHE-RSA-AES128-SHA",
+				TLSProtocolVersion:       "tlsv12",
+				TLSNamedGroup:            "-",
+				DomainName:               "my-network-loadbalancer-c6e77e28c25b2234.elb.us-east-2.amazonaws.com",
+				ALPNFrontendProtocol:     "h2",
+				ALPNBackendProtocol:      "h2",
+				ALPNClientPreferenceList: []string{"h2", "http/1.1"},
+				ConnectionCreationTime:   time.Date(2020, 4, 1, 8, 51, 20, 0, time.UTC),
+				OtherFields:              "future-field",
+			},
+		},
+	}
+
+	for hint, c := range cases {
+		t.Run(hint, func(t *testing.T) {
+			got, err := ParseNLB([]byte(c.given))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			if !reflect.DeepEqual(*got, c.expected) {
+				t.Errorf("expected:\n	%v but got:\n	%v", c.expected, *got)
+			}
+		})
+	}
+}
+
+func TestParseNLB_invalid(t *testing.T) {
+	cases := map[string]string{
+		"time":            `tls 2.0 yesterday net/my-network-loadbalancer/c6e77e28c25b2234 g3d4b5e8bb8464cd 72.21.218.154:51341 172.100.100.185:443 5 2 98 246`,
+		"connection-time": `tls 2.0 2018-12-20T02:59:40 net/my-network-loadbalancer/c6e77e28c25b2234 g3d4b5e8bb8464cd 72.21.218.154:51341 172.100.100.185:443 - 2 98 246`,
+		"received-bytes":  `tls 2.0 2018-12-20T02:59:40 net/my-network-loadbalancer/c6e77e28c25b2234 g3d4b5e8bb8464cd 72.21.218.154:51341 172.100.100.185:443 5 2 many 246`,
+	}
+
+	for hint, given := range cases {
+		t.Run(hint, func(t *testing.T) {
+			if _, err := ParseNLB([]byte(given)); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestNLBDecoder_Decode(t *testing.T) {
+	expected := 100
+	buf := bytes.NewBuffer(nil)
+	for i := 0; i < expected; i++ {
+		buf.WriteString(`tls 2.0 2018-12-20T02:59:40 net/my-network-loadbalancer/c6e77e28c25b2234 g3d4b5e8bb8464cd 72.21.218.154:51341 172.100.100.185:443 5 2 98 246 - arn:aws:acm:us-east-2:671290407336:certificate/2a108f19-aded-46b0-8493-c63eb1ef4a99 - ECDHE-RSA-AES128-SHA tlsv12 - my-network-loadbalancer-c6e77e28c25b2234.elb.us-east-2.amazonaws.com - - - 2018-12-20T02:59:30`)
+		buf.WriteRune('\n')
+	}
+	dec := NewNLBDecoder(buf)
+	got := make([]*NLBLog, 0, expected)
+	for dec.More() {
+		log, err := dec.Decode()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		got = append(got, log)
+	}
+	if len(got) != expected {
+		t.Errorf("wrong length, expected %d but got %d", expected, len(got))
+	}
+}
+
+func TestNLBDecoder_Decode_readError(t *testing.T) {
+	line := `tls 2.0 2018-12-20T02:59:40 net/my-network-loadbalancer/c6e77e28c25b2234 g3d4b5e8bb8464cd 72.21.218.154:51341 172.100.100.185:443 5 2 98 246 - arn:aws:acm:us-east-2:671290407336:certificate/2a108f19-aded-46b0-8493-c63eb1ef4a99 - ECDHE-RSA-AES128-SHA tlsv12 - my-network-loadbalancer-c6e77e28c25b2234.elb.us-east-2.amazonaws.com - - - 2018-12-20T02:59:30`
+	readErr := errors.New("read error")
+	dec := NewNLBDecoder(io.MultiReader(strings.NewReader(line+"\n"+line[:40]), iotest.ErrReader(readErr)))
+
+	var n int
+	var got error
+	for dec.More() {
+		_, err := dec.Decode()
+		if err != nil {
+			got = err
+			continue
+		}
+		n++
+	}
+	if n != 1 || got != readErr {
+		t.Errorf("expected 1 log and the read error, got %d logs and error: %v", n, got)
+	}
+	if _, err := dec.Decode(); err != io.EOF {
+		t.Errorf("expected EOF after the read error, got: %v", err)
+	}
+}
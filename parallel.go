@@ -0,0 +1,289 @@
+package elblog
+
+import (
+	"bytes"
+	"io"
+	"iter"
+	"runtime"
+	"sync"
+)
+
+// linesPerWorker limits the number of lines read ahead by ParallelDecoder.
+const linesPerWorker = 64
+
+// WithUnordered makes ParallelDecoder return logs as soon as they are parsed, instead of in the input order.
+// It has no effect on Decoder.
+func WithUnordered() DecoderOption {
+	return func(d *Decoder) {
+		d.unordered = true
+	}
+}
+
+// ParallelDecoder reads ALB logs from an input stream like Decoder does, but parses them concurrently.
+// Lines are read by a single goroutine and copied, so workers never share memory with the read buffer.
+// Logs are returned in the input order, unless WithUnordered is set.
+// The error policy and the skip handler are applied in the goroutine calling Decode.
+// Close must be called if the input is not read until EOF, otherwise the goroutines leak.
+type ParallelDecoder struct {
+	dec     *Decoder
+	workers int
+	start   sync.Once
+	stop    sync.Once
+
+	lines   chan parallelLine
+	results chan parallelResult
+	// tokens limits the number of lines read but not returned by Decode yet.
+	tokens chan struct{}
+	done   chan struct{}
+
+	// next is the number of the next result in the input order, buffered are results parsed ahead of it.
+	next     int
+	buffered map[int]parallelResult
+
+	pending    decoded
+	hasPending bool
+	line       int
+	offset     int64
+}
+
+// parallelLine is a line read by ParallelDecoder, it is owned by the worker that receives it.
+type parallelLine struct {
+	seq       int
+	data      []byte
+	line      int
+	start     int64
+	offset    int64
+	oversized bool
+}
+
+type parallelResult struct {
+	decoded
+	seq   int
+	data  []byte
+	start int64
+	// readErr is true if the result is the read error and not a line.
+	readErr bool
+}
+
+// NewParallelDecoder allocates new ParallelDecoder object for given input, which parses lines using given number
+// of workers. If workers is not positive, runtime.GOMAXPROCS(0) is used. Options are the same as for Decoder.
+func NewParallelDecoder(r io.Reader, workers int, opts ...DecoderOption) *ParallelDecoder {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	return &ParallelDecoder{
+		dec:      NewDecoder(r, opts...),
+		workers:  workers,
+		lines:    make(chan parallelLine, workers),
+		results:  make(chan parallelResult, workers),
+		tokens:   make(chan struct{}, workers*linesPerWorker),
+		done:     make(chan struct{}),
+		buffered: make(map[int]parallelResult),
+	}
+}
+
+// Decode returns the next parsed Log. It returns EOF if there is nothing left or the decoder is closed.
+// Parse errors are returned as *ParseError with Line set. Read errors are returned once, then Decode returns EOF.
+func (p *ParallelDecoder) Decode() (*Log, error) {
+	res := p.pending
+	if p.hasPending {
+		p.pending, p.hasPending = decoded{}, false
+	} else {
+		res = p.decode()
+	}
+	p.line, p.offset = res.line, res.offset
+	return res.log, res.err
+}
+
+// More return true if there is anything left to decode. Lines skipped because of the error policy are not taken into account.
+func (p *ParallelDecoder) More() bool {
+	if !p.hasPending {
+		p.pending, p.hasPending = p.decode(), true
+	}
+	return p.pending.err != io.EOF
+}
+
+// All returns an iterator over decoded logs, see Decoder.All.
+func (p *ParallelDecoder) All() iter.Seq2[*Log, error] {
+	return func(yield func(*Log, error) bool) {
+		for {
+			log, err := p.Decode()
+			if err == io.EOF {
+				return
+			}
+			if !yield(log, err) {
+				return
+			}
+		}
+	}
+}
+
+// Line returns the number of the line last returned by Decode, starting at 1.
+func (p *ParallelDecoder) Line() int {
+	return p.line
+}
+
+// Offset returns the byte offset right after the line last returned by Decode.
+func (p *ParallelDecoder) Offset() int64 {
+	return p.offset
+}
+
+// Skipped returns lines skipped so far. It is populated only if ErrorPolicyCollect is set.
+func (p *ParallelDecoder) Skipped() []SkippedLine {
+	return p.dec.Skipped()
+}
+
+// Close stops reading and parsing, Decode returns EOF afterwards. A read that is in progress is not interrupted,
+// the reading goroutine exits once it returns.
+func (p *ParallelDecoder) Close() {
+	p.stop.Do(func() {
+		close(p.done)
+	})
+}
+
+func (p *ParallelDecoder) decode() decoded {
+	p.start.Do(p.run)
+	for {
+		if p.closed() {
+			return decoded{err: io.EOF, line: p.line, offset: p.offset}
+		}
+		res, ok := p.receive()
+		if !ok {
+			if p.closed() {
+				return decoded{err: io.EOF, line: p.line, offset: p.offset}
+			}
+			// results are closed, so the reading goroutine is done
+			return decoded{err: io.EOF, line: p.dec.lines.line, offset: p.dec.lines.offset}
+		}
+		<-p.tokens
+		if res.err == errFiltered {
+			continue
+		}
+		if !res.readErr && p.dec.skipLine(res.data, res.line, res.start, res.err) {
+			continue
+		}
+		return res.decoded
+	}
+}
+
+func (p *ParallelDecoder) closed() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// receive returns the next result, in the input order unless the decoder is unordered.
+func (p *ParallelDecoder) receive() (parallelResult, bool) {
+	for {
+		if res, ok := p.buffered[p.next]; ok {
+			delete(p.buffered, p.next)
+			p.next++
+			return res, true
+		}
+		select {
+		case res, ok := <-p.results:
+			if !ok {
+				return parallelResult{}, false
+			}
+			if p.dec.unordered {
+				return res, true
+			}
+			p.buffered[res.seq] = res
+		case <-p.done:
+			return parallelResult{}, false
+		}
+	}
+}
+
+func (p *ParallelDecoder) run() {
+	var wg sync.WaitGroup
+	wg.Add(p.workers + 1)
+	go func() {
+		defer wg.Done()
+		p.read()
+	}()
+	for range p.workers {
+		go func() {
+			defer wg.Done()
+			p.work()
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(p.results)
+	}()
+}
+
+// read reads lines and hands them over to workers. The read error, if any, is sent directly as the last result.
+func (p *ParallelDecoder) read() {
+	defer close(p.lines)
+
+	lines := p.dec.lines
+	for seq := 0; ; seq++ {
+		select {
+		case p.tokens <- struct{}{}:
+		case <-p.done:
+			return
+		}
+		b, ok := lines.next()
+		if !ok {
+			if err := lines.readErr(); err != nil {
+				p.send(parallelResult{
+					decoded: decoded{err: err, line: lines.line, offset: lines.offset},
+					seq:     seq,
+					readErr: true,
+				})
+			}
+			return
+		}
+		l := parallelLine{
+			seq:       seq,
+			data:      bytes.Clone(b),
+			line:      lines.line,
+			start:     lines.start,
+			offset:    lines.offset,
+			oversized: lines.oversized,
+		}
+		select {
+		case p.lines <- l:
+		case <-p.done:
+			return
+		}
+	}
+}
+
+func (p *ParallelDecoder) work() {
+	for {
+		select {
+		case l, ok := <-p.lines:
+			if !ok {
+				return
+			}
+			// the line is not modified once read, so logs can refer to it
+			log, err := p.dec.parseLine(l.data, l.line, l.oversized, true)
+			res := parallelResult{
+				decoded: decoded{log: log, err: err, line: l.line, offset: l.offset},
+				seq:     l.seq,
+				data:    l.data,
+				start:   l.start,
+			}
+			if !p.send(res) {
+				return
+			}
+		case <-p.done:
+			return
+		}
+	}
+}
+
+func (p *ParallelDecoder) send(res parallelResult) bool {
+	select {
+	case p.results <- res:
+		return true
+	case <-p.done:
+		return false
+	}
+}
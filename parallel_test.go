@@ -0,0 +1,154 @@
+package elblog
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// parallelInput returns n lines, every 7th of them is invalid.
+func parallelInput(n int) string {
+	lines := make([]string, 0, n)
+	for i := range n {
+		line := validLine
+		if i%7 == 6 {
+			line = invalidLine
+		}
+		// different sizes make sure the lines are parsed in a different order than they are read
+		lines = append(lines, strings.Replace(line, "curl/7.38.0", "curl/"+strings.Repeat("7", i%13), 1))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestParallelDecoder_Decode(t *testing.T) {
+	input := parallelInput(1000)
+
+	cases := map[string][]DecoderOption{
+		"fail":    nil,
+		"skip":    {WithErrorPolicy(ErrorPolicySkip)},
+		"collect": {WithErrorPolicy(ErrorPolicyCollect)},
+	}
+
+	for hint, opts := range cases {
+		t.Run(hint, func(t *testing.T) {
+			var expected, got []decoded
+			dec := NewDecoder(strings.NewReader(input), opts...)
+			for dec.More() {
+				log, err := dec.Decode()
+				expected = append(expected, decoded{log: log, err: err, line: dec.Line(), offset: dec.Offset()})
+			}
+
+			pdec := NewParallelDecoder(strings.NewReader(input), 4, opts...)
+			for pdec.More() {
+				log, err := pdec.Decode()
+				got = append(got, decoded{log: log, err: err, line: pdec.Line(), offset: pdec.Offset()})
+			}
+			if _, err := pdec.Decode(); err != io.EOF {
+				t.Errorf("expected EOF, got: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, expected) {
+				t.Errorf("parallel decoder should return the same results as decoder, got %d results instead of %d", len(got), len(expected))
+			}
+			if !reflect.DeepEqual(pdec.Skipped(), dec.Skipped()) {
+				t.Errorf("wrong skipped lines, expected %d but got %d", len(dec.Skipped()), len(pdec.Skipped()))
+			}
+			if pdec.Line() != dec.Line() || pdec.Offset() != dec.Offset() {
+				t.Errorf("wrong position at EOF, expected %d, %d but got %d, %d", dec.Line(), dec.Offset(), pdec.Line(), pdec.Offset())
+			}
+		})
+	}
+}
+
+func TestParallelDecoder_Decode_unordered(t *testing.T) {
+	input := parallelInput(1000)
+	var lines []int
+	var errs int
+	dec := NewParallelDecoder(strings.NewReader(input), 4, WithUnordered())
+	for _, err := range dec.All() {
+		if err != nil {
+			var perr *ParseError
+			if !errors.As(err, &perr) || perr.Line != dec.Line() {
+				t.Fatalf("expected ParseError at line %d but got: %v", dec.Line(), err)
+			}
+			errs++
+		}
+		lines = append(lines, dec.Line())
+	}
+	if len(lines) != 1000 || errs != 1000/7 {
+		t.Fatalf("wrong number of results, expected 1000 with %d errors but got %d with %d", 1000/7, len(lines), errs)
+	}
+	sort.Ints(lines)
+	for i, line := range lines {
+		if line != i+1 {
+			t.Fatalf("line %d is missing", i+1)
+		}
+	}
+}
+
+func TestParallelDecoder_Decode_readError(t *testing.T) {
+	input := strings.Repeat(validLine+"\n", 100)
+	// the reader fails after the first read, which ends in the middle of a line
+	dec := NewParallelDecoder(iotest.TimeoutReader(strings.NewReader(input)), 2)
+	var n, errs int
+	for {
+		log, err := dec.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if err != iotest.ErrTimeout {
+				t.Fatalf("expected ErrTimeout, got: %v", err)
+			}
+			errs++
+			continue
+		}
+		if log.Version == VersionUnknown {
+			t.Errorf("partial line should not be decoded: %s", log.Request)
+		}
+		n++
+	}
+	if expected := 4096 / (len(validLine) + 1); n != expected || errs != 1 {
+		t.Errorf("expected %d logs and 1 error, got %d logs and %d errors", expected, n, errs)
+	}
+}
+
+func TestParallelDecoder_Close(t *testing.T) {
+	input := parallelInput(10000)
+	dec := NewParallelDecoder(strings.NewReader(input), 4)
+	for range dec.All() {
+		if dec.Line() == 10 {
+			break
+		}
+	}
+	dec.Close()
+	dec.Close()
+	if _, err := dec.Decode(); err != io.EOF {
+		t.Errorf("expected EOF after Close, got: %v", err)
+	}
+}
+
+func BenchmarkParallelDecoder_Decode(b *testing.B) {
+	input := buffor(10000).String()
+	for hint, opts := range map[string][]DecoderOption{
+		"ordered":   nil,
+		"unordered": {WithUnordered()},
+	} {
+		b.Run(hint, func(b *testing.B) {
+			b.ReportAllocs()
+			for n := 0; n < b.N; n++ {
+				dec := NewParallelDecoder(strings.NewReader(input), 0, opts...)
+				for log, err := range dec.All() {
+					if err != nil {
+						b.Fatalf("unexpected error: %s", err.Error())
+					}
+					benchLog = *log
+				}
+			}
+		})
+	}
+}
@@ -1,8 +1,8 @@
 package schemas
 
 import (
+	"net"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/Clever/elblog"
@@ -12,62 +12,76 @@ import (
 // https://docs.aws.amazon.com/elasticloadbalancing/latest/application/load-balancer-access-logs.html
 // https://github.com/xitongsys/parquet-go
 type ALBLogSchema struct {
-	Type                   string  `parquet:"name=type, type=UTF8, encoding=PLAIN_DICTIONARY"`
-	Time                   string  `parquet:"name=time, type=UTF8"`
-	ELB                    string  `parquet:"name=elb, type=UTF8, encoding=PLAIN_DICTIONARY"`
-	ClientIP               string  `parquet:"name=client_ip, type=UTF8"`
-	ClientPort             int32   `parquet:"name=client_port, type=INT32"`
-	TargetIP               string  `parquet:"name=target_ip, type=UTF8"`
-	TargetPort             int32   `parquet:"name=target_port, type=INT32"`
-	RequestProcessingTime  float64 `parquet:"name=request_processing_time, type=DOUBLE"`
-	TargetProcessingTime   float64 `parquet:"name=target_processing_time, type=DOUBLE"`
-	ResponseProcessingTime float64 `parquet:"name=response_processing_time, type=DOUBLE"`
-	ELBStatusCode          string  `parquet:"name=elb_status_code, type=UTF8, encoding=PLAIN_DICTIONARY"`
-	TargetStatusCode       string  `parquet:"name=target_status_code, type=UTF8, encoding=PLAIN_DICTIONARY"`
-	ReceivedBytes          int64   `parquet:"name=received_bytes, type=INT64"`
-	SentBytes              int64   `parquet:"name=sent_bytes, type=INT64"`
-	RequestVerb            string  `parquet:"name=request_verb, type=UTF8, encoding=PLAIN_DICTIONARY"`
-	RequestURL             string  `parquet:"name=request_url, type=UTF8"`
-	RequestProto           string  `parquet:"name=request_proto, type=UTF8, encoding=PLAIN_DICTIONARY"`
-	UserAgent              string  `parquet:"name=user_agent, type=UTF8"`
-	SSLCipher              string  `parquet:"name=ssl_cipher, type=UTF8, encoding=PLAIN_DICTIONARY"`
-	SSLProtocol            string  `parquet:"name=ssl_protocol, type=UTF8, encoding=PLAIN_DICTIONARY"`
-	TargetGroupARN         string  `parquet:"name=target_group_arn, type=UTF8, encoding=PLAIN_DICTIONARY"`
-	TraceID                string  `parquet:"name=trace_id, type=UTF8"`
-	DomainName             string  `parquet:"name=domain_name, type=UTF8, encoding=PLAIN_DICTIONARY"`
-	ChosenCertARN          string  `parquet:"name=chosen_cert_arn, type=UTF8"`
-	MatchedRulePriority    string  `parquet:"name=matched_rule_priority, type=UTF8, encoding=PLAIN_DICTIONARY"`
-	RequestCreationTime    string  `parquet:"name=request_creation_time, type=UTF8"`
-	ActionsExecuted        string  `parquet:"name=actions_executed, type=UTF8, encoding=PLAIN_DICTIONARY"`
-	RedirectURL            string  `parquet:"name=redirect_url, type=UTF8"`
-	ErrorReason            string  `parquet:"name=error_reason, type=UTF8, encoding=PLAIN_DICTIONARY"`
-	TargetPortList         string  `parquet:"name=target_port_list, type=UTF8"`
-	TargetStatusCodeList   string  `parquet:"name=target_status_code_list, type=UTF8"`
-	Classification         string  `parquet:"name=classification, type=UTF8, encoding=PLAIN_DICTIONARY"`
-	ClassificationReason   string  `parquet:"name=classification_reason, type=UTF8, encoding=PLAIN_DICTIONARY"`
-	OtherFields            string  `parquet:"name=other_fields, type=UTF8"`
+	Type                   string   `parquet:"name=type, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	Time                   string   `parquet:"name=time, type=UTF8"`
+	ELB                    string   `parquet:"name=elb, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	ClientIP               string   `parquet:"name=client_ip, type=UTF8"`
+	ClientPort             int32    `parquet:"name=client_port, type=INT32"`
+	TargetIP               string   `parquet:"name=target_ip, type=UTF8"`
+	TargetPort             int32    `parquet:"name=target_port, type=INT32"`
+	RequestProcessingTime  *float64 `parquet:"name=request_processing_time, type=DOUBLE, repetitiontype=OPTIONAL"`
+	TargetProcessingTime   *float64 `parquet:"name=target_processing_time, type=DOUBLE, repetitiontype=OPTIONAL"`
+	ResponseProcessingTime *float64 `parquet:"name=response_processing_time, type=DOUBLE, repetitiontype=OPTIONAL"`
+	ELBStatusCode          string   `parquet:"name=elb_status_code, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	TargetStatusCode       *string  `parquet:"name=target_status_code, type=UTF8, encoding=PLAIN_DICTIONARY, repetitiontype=OPTIONAL"`
+	ReceivedBytes          int64    `parquet:"name=received_bytes, type=INT64"`
+	SentBytes              int64    `parquet:"name=sent_bytes, type=INT64"`
+	RequestVerb            string   `parquet:"name=request_verb, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	RequestURL             string   `parquet:"name=request_url, type=UTF8"`
+	RequestProto           string   `parquet:"name=request_proto, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	UserAgent              string   `parquet:"name=user_agent, type=UTF8"`
+	SSLCipher              string   `parquet:"name=ssl_cipher, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	SSLProtocol            string   `parquet:"name=ssl_protocol, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	TargetGroupARN         string   `parquet:"name=target_group_arn, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	TraceID                string   `parquet:"name=trace_id, type=UTF8"`
+	DomainName             string   `parquet:"name=domain_name, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	ChosenCertARN          string   `parquet:"name=chosen_cert_arn, type=UTF8"`
+	MatchedRulePriority    string   `parquet:"name=matched_rule_priority, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	RequestCreationTime    string   `parquet:"name=request_creation_time, type=UTF8"`
+	ActionsExecuted        string   `parquet:"name=actions_executed, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	RedirectURL            string   `parquet:"name=redirect_url, type=UTF8"`
+	ErrorReason            string   `parquet:"name=error_reason, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	TargetPortList         string   `parquet:"name=target_port_list, type=UTF8"`
+	TargetStatusCodeList   string   `parquet:"name=target_status_code_list, type=UTF8"`
+	Classification         string   `parquet:"name=classification, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	ClassificationReason   string   `parquet:"name=classification_reason, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	OtherFields            string   `parquet:"name=other_fields, type=UTF8"`
+	Version                string   `parquet:"name=version, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	// Region and AccountID are taken from TargetGroupARN, or from ChosenCertARN if there is no target group.
+	Region          string `parquet:"name=region, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	AccountID       string `parquet:"name=account_id, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	TargetGroupName string `parquet:"name=target_group_name, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	TargetGroupID   string `parquet:"name=target_group_id, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	ChosenCertID    string `parquet:"name=chosen_cert_id, type=UTF8, encoding=PLAIN_DICTIONARY"`
 }
 
 // ELBLogToALBLogSchema converts an elblog to an ALBLogSchema that has tags for parquet
 func ELBLogToALBLogSchema(log elblog.Log) ALBLogSchema {
+	// malformed ARNs are kept only in the raw columns
+	tg, _ := log.TargetGroup()
+	cert, _ := log.Certificate()
+	arn := tg.ARN
+	if arn == (elblog.ARN{}) {
+		arn = cert.ARN
+	}
 	return ALBLogSchema{
 		Type:                   log.Type,
 		Time:                   log.Time.Format(time.RFC3339Nano),
 		ELB:                    log.Name,
-		ClientIP:               log.From.IP.String(),
-		ClientPort:             int32(log.From.Port),
-		TargetIP:               log.To.IP.String(),
-		TargetPort:             int32(log.To.Port),
-		RequestProcessingTime:  log.RequestProcessingTime.Seconds(),
-		TargetProcessingTime:   log.BackendProcessingTime.Seconds(),
-		ResponseProcessingTime: log.ResponseProcessingTime.Seconds(),
+		ClientIP:               addrIP(log.From),
+		ClientPort:             addrPort(log.From),
+		TargetIP:               addrIP(log.To),
+		TargetPort:             addrPort(log.To),
+		RequestProcessingTime:  seconds(log.RequestProcessingTime),
+		TargetProcessingTime:   seconds(log.BackendProcessingTime),
+		ResponseProcessingTime: seconds(log.ResponseProcessingTime),
 		ELBStatusCode:          strconv.Itoa(log.ELBStatusCode),
-		TargetStatusCode:       log.BackendStatusCode,
+		TargetStatusCode:       statusCode(log.BackendStatusCode),
 		ReceivedBytes:          log.ReceivedBytes,
 		SentBytes:              log.SentBytes,
-		RequestVerb:            strings.Split(log.Request, " ")[0],
-		RequestURL:             strings.Split(log.Request, " ")[1],
-		RequestProto:           strings.Split(log.Request, " ")[2],
+		RequestVerb:            log.RequestLine.Method,
+		RequestURL:             log.RequestLine.RequestURI,
+		RequestProto:           log.RequestLine.Proto,
 		UserAgent:              log.UserAgent,
 		SSLCipher:              log.SSLCipher,
 		SSLProtocol:            log.SSLProtocol,
@@ -79,11 +93,49 @@ func ELBLogToALBLogSchema(log elblog.Log) ALBLogSchema {
 		RequestCreationTime:    log.RequestCreationTime,
 		ActionsExecuted:        log.ActionsExecuted,
 		RedirectURL:            log.RedirectURL,
-		ErrorReason:            log.ErrorReason,
+		ErrorReason:            string(log.ErrorReason),
 		TargetPortList:         log.TargetPortList,
 		TargetStatusCodeList:   log.TargetStatusCodeList,
-		Classification:         log.Classification,
+		Classification:         string(log.Classification),
 		ClassificationReason:   log.ClassificationReason,
 		OtherFields:            log.OtherFields,
+		Version:                log.Version.String(),
+		Region:                 arn.Region,
+		AccountID:              arn.AccountID,
+		TargetGroupName:        tg.Name,
+		TargetGroupID:          tg.ID,
+		ChosenCertID:           cert.ID,
+	}
+}
+
+// addrIP returns ip of given address or an empty string if there is none (e.g. "-" target).
+func addrIP(addr *net.TCPAddr) string {
+	if addr == nil {
+		return ""
+	}
+	return addr.IP.String()
+}
+
+func addrPort(addr *net.TCPAddr) int32 {
+	if addr == nil {
+		return 0
+	}
+	return int32(addr.Port)
+}
+
+// seconds returns nil if given duration is not available, so it is stored as null.
+func seconds(d elblog.NullDuration) *float64 {
+	if !d.Valid {
+		return nil
+	}
+	s := d.Duration.Seconds()
+	return &s
+}
+
+func statusCode(code elblog.NullInt) *string {
+	if !code.Valid {
+		return nil
 	}
+	s := strconv.Itoa(code.Int)
+	return &s
 }
@@ -0,0 +1,128 @@
+package elblog
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoParent is returned by Trace.Traceparent if the trace has no parent, which W3C trace context requires.
+var ErrNoParent = errors.New("elblog: trace has no parent")
+
+// Trace is X-Amzn-Trace-Id header, logged by ALB as TraceID.
+// See https://docs.aws.amazon.com/elasticloadbalancing/latest/application/load-balancer-request-tracing.html
+type Trace struct {
+	// Root is the whole root trace ID, e.g. 1-67891233-abcdef012345678912345678.
+	Root string
+	// Timestamp is the time the root trace was created, with second precision.
+	Timestamp time.Time
+	// ID is the 96-bit identifier of the root trace, as 24 hexadecimal digits.
+	ID string
+	// Parent is the 64-bit identifier of the parent segment, as 16 hexadecimal digits. It is empty if not set.
+	Parent string
+	// Sampled is true if the request is sampled (Sampled=1).
+	Sampled bool
+	// Self is the trace ID added by load balancer if the request already had a root. It is empty if not set.
+	Self string
+	// Fields are custom key-value pairs, nil if there are none.
+	Fields map[string]string
+}
+
+// Trace returns TraceID parsed as Trace. It returns the zero Trace if the field is "-" or missing.
+func (l *Log) Trace() (Trace, error) {
+	if isEmptyField(l.TraceID) {
+		return Trace{}, nil
+	}
+	return ParseTrace(l.TraceID)
+}
+
+// ParseTrace parses X-Amzn-Trace-Id header value, e.g. Root=1-67891233-abcdef012345678912345678;Sampled=1.
+func ParseTrace(s string) (Trace, error) {
+	var t Trace
+	for part := range strings.SplitSeq(s, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return Trace{}, fmt.Errorf("missing '=' in %q", part)
+		}
+		switch key {
+		case "Root":
+			ts, id, err := parseTraceRoot(value)
+			if err != nil {
+				return Trace{}, err
+			}
+			t.Root, t.Timestamp, t.ID = value, ts, id
+		case "Parent":
+			if len(value) != 16 || !isHex(value) {
+				return Trace{}, fmt.Errorf("invalid trace parent %q", value)
+			}
+			t.Parent = value
+		case "Sampled":
+			switch value {
+			case "0", "?":
+			case "1":
+				t.Sampled = true
+			default:
+				return Trace{}, fmt.Errorf("invalid trace sampling decision %q", value)
+			}
+		case "Self":
+			t.Self = value
+		default:
+			if t.Fields == nil {
+				t.Fields = make(map[string]string)
+			}
+			t.Fields[key] = value
+		}
+	}
+	if t.Root == "" {
+		return Trace{}, fmt.Errorf("missing trace root in %q", s)
+	}
+	return t, nil
+}
+
+// parseTraceRoot parses root trace ID: version 1, the epoch time as 8 hexadecimal digits and the identifier as 24 ones.
+func parseTraceRoot(s string) (time.Time, string, error) {
+	version, rest, _ := strings.Cut(s, "-")
+	epoch, id, _ := strings.Cut(rest, "-")
+	if version != "1" || len(epoch) != 8 || len(id) != 24 || !isHex(id) {
+		return time.Time{}, "", fmt.Errorf("invalid trace root %q", s)
+	}
+	sec, err := strconv.ParseUint(epoch, 16, 32)
+	if err != nil {
+		return time.Time{}, "", fmt.Errorf("invalid trace root %q", s)
+	}
+	return time.Unix(int64(sec), 0).UTC(), id, nil
+}
+
+// Traceparent returns the trace as W3C traceparent header, e.g. 00-67891233abcdef012345678912345678-463ac35c9f6413ad-01.
+// The trace ID is the root epoch time followed by its identifier, the parent ID is Parent.
+// It returns ErrNoParent if Parent is not set.
+func (t Trace) Traceparent() (string, error) {
+	if t.ID == "" {
+		return "", errors.New("elblog: trace has no root")
+	}
+	if t.Parent == "" {
+		return "", ErrNoParent
+	}
+	flags := "00"
+	if t.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%08x%s-%s-%s", t.Timestamp.Unix(), strings.ToLower(t.ID), strings.ToLower(t.Parent), flags), nil
+}
+
+func isHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case '0' <= c && c <= '9', 'a' <= c && c <= 'f', 'A' <= c && c <= 'F':
+		default:
+			return false
+		}
+	}
+	return true
+}
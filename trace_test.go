@@ -0,0 +1,105 @@
+package elblog
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseTrace(t *testing.T) {
+	cases := map[string]struct {
+		given    string
+		expected Trace
+		err      bool
+	}{
+		"root": {
+			given: "Root=1-58337262-36d228ad5d99923122bbe354",
+			expected: Trace{
+				Root:      "1-58337262-36d228ad5d99923122bbe354",
+				Timestamp: time.Date(2016, 11, 21, 22, 17, 6, 0, time.UTC),
+				ID:        "36d228ad5d99923122bbe354",
+			},
+		},
+		"full": {
+			given: "Self=1-67891234-12456789abcdef012345678;Root=1-67891233-abcdef012345678912345678;Parent=463ac35c9f6413ad;Sampled=1;CalledFrom=app",
+			expected: Trace{
+				Root:      "1-67891233-abcdef012345678912345678",
+				Timestamp: time.Unix(0x67891233, 0).UTC(),
+				ID:        "abcdef012345678912345678",
+				Parent:    "463ac35c9f6413ad",
+				Sampled:   true,
+				Self:      "1-67891234-12456789abcdef012345678",
+				Fields:    map[string]string{"CalledFrom": "app"},
+			},
+		},
+		"not-sampled": {
+			given: "Root=1-67891233-abcdef012345678912345678; Sampled=?",
+			expected: Trace{
+				Root:      "1-67891233-abcdef012345678912345678",
+				Timestamp: time.Unix(0x67891233, 0).UTC(),
+				ID:        "abcdef012345678912345678",
+			},
+		},
+		"missing-root":   {given: "Self=1-67891234-12456789abcdef012345678", err: true},
+		"invalid-root":   {given: "Root=2-67891233-abcdef012345678912345678", err: true},
+		"invalid-epoch":  {given: "Root=1-6789123x-abcdef012345678912345678", err: true},
+		"invalid-id":     {given: "Root=1-67891233-abcdef01234567891234567", err: true},
+		"invalid-parent": {given: "Root=1-67891233-abcdef012345678912345678;Parent=463ac35c", err: true},
+		"invalid-sample": {given: "Root=1-67891233-abcdef012345678912345678;Sampled=yes", err: true},
+		"missing-equals": {given: "Root", err: true},
+	}
+
+	for hint, c := range cases {
+		t.Run(hint, func(t *testing.T) {
+			got, err := ParseTrace(c.given)
+			if (err != nil) != c.err {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, c.expected) {
+				t.Errorf("expected:\n	%v but got:\n	%v", c.expected, got)
+			}
+		})
+	}
+}
+
+func TestLog_Trace(t *testing.T) {
+	got, err := (&Log{TraceID: "-"}).Trace()
+	if err != nil || !reflect.DeepEqual(got, Trace{}) {
+		t.Errorf("expected the zero trace, got: %v, %v", got, err)
+	}
+	got, err = (&Log{TraceID: "Root=1-58337262-36d228ad5d99923122bbe354"}).Trace()
+	if err != nil || got.ID != "36d228ad5d99923122bbe354" {
+		t.Errorf("unexpected trace: %v, %v", got, err)
+	}
+}
+
+func TestTrace_Traceparent(t *testing.T) {
+	cases := map[string]struct {
+		given    string
+		expected string
+		err      error
+	}{
+		"sampled":     {given: "Root=1-67891233-ABCDEF012345678912345678;Parent=463ac35c9f6413ad;Sampled=1", expected: "00-67891233abcdef012345678912345678-463ac35c9f6413ad-01"},
+		"not-sampled": {given: "Root=1-67891233-abcdef012345678912345678;Parent=463ac35c9f6413ad", expected: "00-67891233abcdef012345678912345678-463ac35c9f6413ad-00"},
+		"no-parent":   {given: "Root=1-67891233-abcdef012345678912345678;Sampled=1", err: ErrNoParent},
+	}
+
+	for hint, c := range cases {
+		t.Run(hint, func(t *testing.T) {
+			trace, err := ParseTrace(c.given)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+			got, err := trace.Traceparent()
+			if err != c.err {
+				t.Fatalf("expected error %v but got %v", c.err, err)
+			}
+			if got != c.expected {
+				t.Errorf("expected %q but got %q", c.expected, got)
+			}
+		})
+	}
+	if _, err := (Trace{}).Traceparent(); err == nil {
+		t.Error("expected error for the zero trace")
+	}
+}
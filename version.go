@@ -0,0 +1,86 @@
+package elblog
+
+// Version is a generation of the access log format. Load balancer adds new fields at the end of the line,
+// so the version is detected by the number of fields.
+type Version int
+
+const (
+	// VersionUnknown is reported for lines that do not match any known version, e.g. truncated ones.
+	// They are accepted only in ModeLenient.
+	VersionUnknown Version = iota
+	// VersionClassic is the Classic Load Balancer format.
+	VersionClassic
+	// Version2015 is the legacy format, up to ssl_protocol field.
+	Version2015
+	// Version2017 adds target_group_arn, trace_id, domain_name, chosen_cert_arn and matched_rule_priority fields.
+	Version2017
+	// Version2019 adds request_creation_time, actions_executed, redirect_url and error_reason fields.
+	Version2019
+	// Version2020 adds target:port_list, target_status_code_list, classification and classification_reason fields.
+	// Lines with more fields are of this version as well, extra fields go into OtherFields.
+	Version2020
+)
+
+// String returns the name of the version, e.g. "2019".
+func (v Version) String() string {
+	switch v {
+	case VersionClassic:
+		return "classic"
+	case Version2015:
+		return "2015"
+	case Version2017:
+		return "2017"
+	case Version2019:
+		return "2019"
+	case Version2020:
+		return "2020"
+	default:
+		return "unknown"
+	}
+}
+
+// albLayouts are the numbers of fields of ALB lines, fields were not always added at once.
+var albLayouts = []struct {
+	fields  int
+	version Version
+}{
+	{fields: 16, version: Version2015},
+	{fields: 18, version: Version2017},
+	{fields: 20, version: Version2017},
+	{fields: 21, version: Version2017},
+	{fields: 24, version: Version2019},
+	{fields: 25, version: Version2019},
+	{fields: 27, version: Version2020},
+	{fields: 29, version: Version2020},
+}
+
+const numClassicFields = numClassicTokens - 1
+
+// detectVersion returns the version of a line with n fields.
+func detectVersion(n int, format Format) Version {
+	if format == FormatClassic {
+		if n == numClassicFields {
+			return VersionClassic
+		}
+		return VersionUnknown
+	}
+	for _, l := range albLayouts {
+		if l.fields == n {
+			return l.version
+		}
+	}
+	if n > albLayouts[len(albLayouts)-1].fields {
+		return Version2020
+	}
+	return VersionUnknown
+}
+
+// layoutFields returns the smallest number of ALB fields, not less than n, that matches a layout of at least given version.
+func layoutFields(n int, v Version) int {
+	for _, l := range albLayouts {
+		if l.fields >= n && l.version >= v {
+			return l.fields
+		}
+	}
+	return n
+}
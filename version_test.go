@@ -0,0 +1,60 @@
+package elblog
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse_version(t *testing.T) {
+	full := `https 2018-07-02T22:23:00.186641Z app/my-loadbalancer/50dc6c495c0c9188 192.168.131.39:2817 10.0.0.1:80 0.086 0.048 0.037 200 200 0 57 "GET https://www.example.com:443/ HTTP/1.1" "curl/7.46.0" ECDHE-RSA-AES128-GCM-SHA256 TLSv1.2 arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067 "Root=1-58337281-1d84f3d73c47ec4e58577259" "www.example.com" "arn:aws:acm:us-east-2:123456789012:certificate/12345678-1234-1234-1234-123456789012" 1 2018-07-02T22:22:48.364000Z "authenticate,forward" "-" "-" "10.0.0.1:80" "200" "-" "-"`
+	fields := strings.Split(full, " ")
+	// request has 3 parts
+	token := func(n int) string {
+		return strings.Join(fields[:n+2], " ")
+	}
+
+	cases := map[string]struct {
+		given    string
+		expected Version
+	}{
+		"2015":          {given: token(16), expected: Version2015},
+		"2017":          {given: token(18), expected: Version2017},
+		"2017-sni":      {given: token(20), expected: Version2017},
+		"2017-priority": {given: token(21), expected: Version2017},
+		"2019":          {given: token(24), expected: Version2019},
+		"2019-error":    {given: token(25), expected: Version2019},
+		"2020-targets":  {given: token(27), expected: Version2020},
+		"2020":          {given: full, expected: Version2020},
+		"2020-extra":    {given: full + ` "TID_1234" "-"`, expected: Version2020},
+		"truncated":     {given: token(22), expected: VersionUnknown},
+	}
+
+	for hint, c := range cases {
+		t.Run(hint, func(t *testing.T) {
+			got, err := Parse([]byte(c.given))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+			if got.Version != c.expected {
+				t.Errorf("expected %s but got %s", c.expected, got.Version)
+			}
+		})
+	}
+}
+
+func TestVersion_String(t *testing.T) {
+	cases := map[Version]string{
+		VersionUnknown: "unknown",
+		VersionClassic: "classic",
+		Version2015:    "2015",
+		Version2017:    "2017",
+		Version2019:    "2019",
+		Version2020:    "2020",
+		Version(100):   "unknown",
+	}
+	for given, expected := range cases {
+		if got := given.String(); got != expected {
+			t.Errorf("expected %s but got %s", expected, got)
+		}
+	}
+}